@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -54,7 +55,7 @@ func TestListAction(t *testing.T) {
 			}
 			var out bytes.Buffer
 			timeout := 1 * time.Second
-			err := listAction(&out, url, timeout)
+			err := listAction(&out, url, "", timeout)
 			if tc.expError != nil {
 				if err == nil {
 					t.Fatalf("Expected error %q, got no error.", tc.expError)
@@ -204,7 +205,7 @@ func TestCompleteAction(t *testing.T) {
 	// Execute Complete test
 	var out bytes.Buffer
 	timeout := 1 * time.Second
-	if err := completeAction(&out, url, arg, timeout); err != nil {
+	if err := completeAction(&out, url, "", arg, timeout); err != nil {
 		t.Fatalf("Expected no error, got %q.", err)
 	}
 	if expOut != out.String() {
@@ -233,10 +234,217 @@ func TestDelAction(t *testing.T) {
 	// Execute Del test
 	var out bytes.Buffer
 	timeout := 1 * time.Second
-	if err := delAction(&out, url, arg, timeout); err != nil {
+	if err := delAction(&out, url, "", arg, timeout); err != nil {
 		t.Fatalf("Expected no error, got %q.", err)
 	}
 	if expOut != out.String() {
 		t.Errorf("Expected output %q, got %q", expOut, out.String())
 	}
 }
+
+func TestEditAction(t *testing.T) {
+	expURLPath := "/todo/1"
+	expMethod := http.MethodPatch
+	expContentType := "application/merge-patch+json"
+	testCases := []struct {
+		name     string
+		args     []string
+		expBody  string
+		expError error
+		expOut   string
+		resp     struct {
+			Status int
+			Body   string
+		}
+	}{
+		{name: "Edit",
+			args:    []string{"1", "New", "task"},
+			expBody: "{\"task\":\"New task\"}\n",
+			expOut:  "Item number 1 renamed to \"New task\".\n",
+			resp:    testResp["updated"]},
+		{name: "NotFound",
+			args:     []string{"1", "Task"},
+			expBody:  "{\"task\":\"Task\"}\n",
+			expError: ErrNotFound,
+			resp:     testResp["notFound"]},
+		{name: "Invalid",
+			args:     []string{"1", ""},
+			expBody:  "{\"task\":\"\"}\n",
+			expError: ErrInvalid,
+			resp:     testResp["invalid"]},
+		{name: "InvalidID",
+			args:     []string{"a", "Task"},
+			expError: ErrNotNumber,
+			resp:     testResp["noContent"]},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url, cleanup := mockServer(
+				func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != expURLPath {
+						t.Errorf("Expected path %q, got %q", expURLPath, r.URL.Path)
+					}
+					if r.Method != expMethod {
+						t.Errorf("Expected method %q, got %q", expMethod, r.Method)
+					}
+					body, err := io.ReadAll(r.Body)
+					if err != nil {
+						t.Fatal(err)
+					}
+					r.Body.Close()
+					if string(body) != tc.expBody {
+						t.Errorf("Expected body %q, got %q", tc.expBody, string(body))
+					}
+					contentType := r.Header.Get("Content-Type")
+					if contentType != expContentType {
+						t.Errorf("Expected Content-Type %q, got %q", expContentType, contentType)
+					}
+					w.WriteHeader(tc.resp.Status)
+					fmt.Fprintln(w, tc.resp.Body)
+				})
+			defer cleanup()
+			var out bytes.Buffer
+			err := editAction(&out, url, "", 1*time.Second, tc.args)
+			if tc.expError != nil {
+				if err == nil {
+					t.Fatalf("Expected error %q, got no error.", tc.expError)
+				}
+				if !errors.Is(err, tc.expError) {
+					t.Errorf("Expected error %q, got %q.", tc.expError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %q.", err)
+			}
+			if tc.expOut != out.String() {
+				t.Errorf("Expected output %q, got %q", tc.expOut, out.String())
+			}
+		})
+	}
+}
+
+func TestReopenAction(t *testing.T) {
+	expURLPath := "/todo/1"
+	expMethod := http.MethodPatch
+	expBody := "{\"done\":false}\n"
+	expContentType := "application/merge-patch+json"
+	expOut := "Item number 1 reopened.\n"
+	arg := "1"
+	url, cleanup := mockServer(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != expURLPath {
+				t.Errorf("Expected path %q, got %q", expURLPath, r.URL.Path)
+			}
+			if r.Method != expMethod {
+				t.Errorf("Expected method %q, got %q", expMethod, r.Method)
+			}
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+			if string(body) != expBody {
+				t.Errorf("Expected body %q, got %q", expBody, string(body))
+			}
+			contentType := r.Header.Get("Content-Type")
+			if contentType != expContentType {
+				t.Errorf("Expected Content-Type %q, got %q", expContentType, contentType)
+			}
+			w.WriteHeader(testResp["updated"].Status)
+			fmt.Fprintln(w, testResp["updated"].Body)
+		})
+	defer cleanup()
+	// Execute Reopen test
+	var out bytes.Buffer
+	timeout := 1 * time.Second
+	if err := reopenAction(&out, url, "", arg, timeout); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	if expOut != out.String() {
+		t.Errorf("Expected output %q, got %q", expOut, out.String())
+	}
+}
+
+func TestListActionNotModified(t *testing.T) {
+	expETag := `"list"`
+	expOut := "-  1  Task 1\n-  2  Task 2\n"
+	stateFile := filepath.Join(t.TempDir(), "list.json")
+	url, cleanup := mockServer(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", expETag)
+			if r.Header.Get("If-None-Match") == expETag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.WriteHeader(testResp["resultsMany"].Status)
+			fmt.Fprintln(w, testResp["resultsMany"].Body)
+		})
+	defer cleanup()
+	// The second list comes from the state file
+	for i := 0; i < 2; i++ {
+		var out bytes.Buffer
+		if err := listAction(&out, url, stateFile, 1*time.Second); err != nil {
+			t.Fatalf("Expected no error, got %q.", err)
+		}
+		if expOut != out.String() {
+			t.Errorf("Expected output %q, got %q", expOut, out.String())
+		}
+	}
+	s, err := loadState(stateFile, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ETag != expETag {
+		t.Errorf("Expected the tag %s saved, got %q.", expETag, s.ETag)
+	}
+}
+
+func TestCompleteActionChanged(t *testing.T) {
+	expIfMatch := `"item1"`
+	expETag := `"item1-completed"`
+	stateFile := filepath.Join(t.TempDir(), "list.json")
+	url, cleanup := mockServer(
+		func(w http.ResponseWriter, r *http.Request) {
+			if ifMatch := r.Header.Get("If-Match"); ifMatch != expIfMatch {
+				t.Errorf("Expected If-Match %q, got %q", expIfMatch, ifMatch)
+			}
+			w.Header().Set("ETag", expETag)
+			w.WriteHeader(testResp["noContent"].Status)
+		})
+	defer cleanup()
+	s := &listState{APIRoot: url, ETags: map[int]string{1: expIfMatch}}
+	if err := s.save(stateFile); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := completeAction(&out, url, stateFile, "1", 1*time.Second); err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	s, err := loadState(stateFile, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ETags[1] != expETag {
+		t.Errorf("Expected the new tag %s saved, got %q.", expETag, s.ETags[1])
+	}
+
+	// The item changed elsewhere since
+	url, cleanup = mockServer(
+		func(w http.ResponseWriter, r *http.Request) {
+			if ifMatch := r.Header.Get("If-Match"); ifMatch != expETag {
+				t.Errorf("Expected If-Match %q, got %q", expETag, ifMatch)
+			}
+			w.WriteHeader(testResp["changed"].Status)
+			fmt.Fprintln(w, testResp["changed"].Body)
+		})
+	defer cleanup()
+	s.APIRoot = url
+	if err := s.save(stateFile); err != nil {
+		t.Fatal(err)
+	}
+	err = delAction(&out, url, stateFile, "1", 1*time.Second)
+	if !errors.Is(err, ErrListChanged) {
+		t.Fatalf("Expected error %q, got %q.", ErrListChanged, err)
+	}
+}
@@ -18,18 +18,21 @@ var (
 	ErrInvalidResponse = errors.New("Invalid server response")
 	ErrInvalid         = errors.New("Invalid data")
 	ErrNotNumber       = errors.New("Not a number")
+	ErrListChanged     = errors.New("List changed, re-run list")
 )
 
 type item struct {
+	ID          int
 	Task        string
 	Done        bool
 	CreatedAt   time.Time
 	CompletedAt time.Time
 }
 type response struct {
-	Results      []item `json:"results"`
-	Date         int    `json:"date"`
-	TotalResults int    `json:"total_results"`
+	Results      []item         `json:"results"`
+	Date         int            `json:"date"`
+	TotalResults int            `json:"total_results"`
+	ETags        map[int]string `json:"etags"`
 }
 
 func newClient(timeout time.Duration) *http.Client {
@@ -39,25 +42,46 @@ func newClient(timeout time.Duration) *http.Client {
 	return c
 }
 
-func getItems(url string, timeout time.Duration) ([]item, error) {
-	r, err := newClient(timeout).Get(url)
+// getResponse gets the response at url along with its entity tag. With
+// a tag, the response is nil if the server replies it did not change
+// since it was sent with that tag
+func getResponse(url, etag string, timeout time.Duration) (*response, string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %s", ErrConnection, err)
+		return nil, "", err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	r, err := newClient(timeout).Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %s", ErrConnection, err)
 	}
 	defer r.Body.Close()
+	if r.StatusCode == http.StatusNotModified && etag != "" {
+		return nil, etag, nil
+	}
 	if r.StatusCode != http.StatusOK {
 		msg, err := io.ReadAll(r.Body)
 		if err != nil {
-			return nil, fmt.Errorf("Cannot read body: %w", err)
+			return nil, "", fmt.Errorf("Cannot read body: %w", err)
 		}
 		err = ErrInvalidResponse
 		if r.StatusCode == http.StatusNotFound {
 			err = ErrNotFound
 		}
-		return nil, fmt.Errorf("%w: %s", err, msg)
+		return nil, "", fmt.Errorf("%w: %s", err, msg)
 	}
 	var resp response
 	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		return nil, "", err
+	}
+	return &resp, r.Header.Get("ETag"), nil
+}
+
+func getItems(url string, timeout time.Duration) ([]item, error) {
+	resp, _, err := getResponse(url, "", timeout)
+	if err != nil {
 		return nil, err
 	}
 	if resp.TotalResults == 0 {
@@ -66,9 +90,21 @@ func getItems(url string, timeout time.Duration) ([]item, error) {
 	return resp.Results, nil
 }
 
-func getAll(apiRoot string, timeout time.Duration) ([]item, error) {
+// getAll gets the items, or takes them from the state if the list did
+// not change since. The state is updated with the items and their tags
+func getAll(apiRoot string, s *listState, timeout time.Duration) ([]item, error) {
 	u := fmt.Sprintf("%s/todo", apiRoot)
-	return getItems(u, timeout)
+	resp, etag, err := getResponse(u, s.ETag, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if resp != nil {
+		s.ETag, s.Items, s.ETags = etag, resp.Results, resp.ETags
+	}
+	if len(s.Items) == 0 {
+		return nil, fmt.Errorf("%w: No results found", ErrNotFound)
+	}
+	return s.Items, nil
 }
 
 func getOne(apiRoot string, id int, timeout time.Duration) (item, error) {
@@ -83,31 +119,42 @@ func getOne(apiRoot string, id int, timeout time.Duration) (item, error) {
 	return items[0], nil
 }
 
-func sendRequest(url, method, contentType string, timeout time.Duration, expStatus int, body io.Reader) error {
+// sendRequest sends the request, only to be applied if the target still
+// has the entity tag etag when it is not empty. It returns the tag of
+// the target after the request
+func sendRequest(url, method, contentType, etag string, timeout time.Duration, expStatus int, body io.Reader) (string, error) {
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
-		return err
+		return "", err
 	}
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
 	r, err := newClient(timeout).Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer r.Body.Close()
 	if r.StatusCode != expStatus {
 		msg, err := io.ReadAll(r.Body)
 		if err != nil {
-			return fmt.Errorf("Cannot read body: %w", err)
+			return "", fmt.Errorf("Cannot read body: %w", err)
 		}
 		err = ErrInvalidResponse
-		if r.StatusCode == http.StatusNotFound {
+		switch r.StatusCode {
+		case http.StatusNotFound:
 			err = ErrNotFound
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			err = ErrInvalid
+		case http.StatusPreconditionFailed:
+			err = ErrListChanged
 		}
-		return fmt.Errorf("%w: %s", err, msg)
+		return "", fmt.Errorf("%w: %s", err, msg)
 	}
-	return nil
+	return r.Header.Get("ETag"), nil
 }
 
 func addItem(apiRoot, task string, timeout time.Duration) error {
@@ -122,15 +169,61 @@ func addItem(apiRoot, task string, timeout time.Duration) error {
 	if err := json.NewEncoder(&body).Encode(item); err != nil {
 		return err
 	}
-	return sendRequest(u, http.MethodPost, "application/json", timeout, http.StatusCreated, &body)
+	_, err := sendRequest(u, http.MethodPost, "application/json", "", timeout, http.StatusCreated, &body)
+	return err
 }
 
-func completeItem(apiRoot string, id int, timeout time.Duration) error {
+// The functions changing an item only do so if it did not change since
+// it was listed, and record its new tag in the state
+
+func completeItem(apiRoot string, id int, s *listState, timeout time.Duration) error {
 	u := fmt.Sprintf("%s/todo/%d?complete", apiRoot, id)
-	return sendRequest(u, http.MethodPatch, "", timeout, http.StatusNoContent, nil)
+	etag, err := sendRequest(u, http.MethodPatch, "", s.ETags[id], timeout, http.StatusNoContent, nil)
+	if err != nil {
+		return err
+	}
+	s.changed(id, etag)
+	return nil
 }
 
-func deleteItem(apiRoot string, id int, timeout time.Duration) error {
+func deleteItem(apiRoot string, id int, s *listState, timeout time.Duration) error {
 	u := fmt.Sprintf("%s/todo/%d", apiRoot, id)
-	return sendRequest(u, http.MethodDelete, "", timeout, http.StatusNoContent, nil)
+	if _, err := sendRequest(u, http.MethodDelete, "", s.ETags[id], timeout, http.StatusNoContent, nil); err != nil {
+		return err
+	}
+	s.changed(id, "")
+	return nil
+}
+
+// patchItem changes the item with the fields of patch, sent as a JSON Merge Patch
+func patchItem(apiRoot string, id int, patch any, s *listState, timeout time.Duration) error {
+	u := fmt.Sprintf("%s/todo/%d", apiRoot, id)
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(patch); err != nil {
+		return err
+	}
+	etag, err := sendRequest(u, http.MethodPatch, "application/merge-patch+json", s.ETags[id], timeout, http.StatusOK, &body)
+	if err != nil {
+		return err
+	}
+	s.changed(id, etag)
+	return nil
+}
+
+func editItem(apiRoot string, id int, task string, s *listState, timeout time.Duration) error {
+	patch := struct {
+		Task string `json:"task"`
+	}{
+		Task: task,
+	}
+	return patchItem(apiRoot, id, patch, s, timeout)
+}
+
+func reopenItem(apiRoot string, id int, s *listState, timeout time.Duration) error {
+	patch := struct {
+		Done bool `json:"done"`
+	}{
+		Done: false,
+	}
+	return patchItem(apiRoot, id, patch, s, timeout)
 }
@@ -40,17 +40,21 @@ var completeCmd = &cobra.Command{
 	Args:         cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		apiRoot := viper.GetString("api-root")
+		stateFile := viper.GetString("state-file")
 		timeout := viper.GetDuration("timeout")
-		return completeAction(os.Stdout, apiRoot, args[0], timeout)
+		return completeAction(os.Stdout, apiRoot, stateFile, args[0], timeout)
 	},
 }
 
-func completeAction(out io.Writer, apiRoot, arg string, timeout time.Duration) error {
+func completeAction(out io.Writer, apiRoot, stateFile, arg string, timeout time.Duration) error {
 	id, err := strconv.Atoi(arg)
 	if err != nil {
 		return err
 	}
-	if err := completeItem(apiRoot, id, timeout); err != nil {
+	err = withState(stateFile, apiRoot, func(s *listState) error {
+		return completeItem(apiRoot, id, s, timeout)
+	})
+	if err != nil {
 		return err
 	}
 	return printComplete(out, id)
@@ -40,17 +40,21 @@ var deleteCmd = &cobra.Command{
 	Args:         cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		apiRoot := viper.GetString("api-root")
+		stateFile := viper.GetString("state-file")
 		timeout := viper.GetDuration("timeout")
-		return delAction(os.Stdout, apiRoot, args[0], timeout)
+		return delAction(os.Stdout, apiRoot, stateFile, args[0], timeout)
 	},
 }
 
-func delAction(out io.Writer, apiRoot, arg string, timeout time.Duration) error {
+func delAction(out io.Writer, apiRoot, stateFile, arg string, timeout time.Duration) error {
 	id, err := strconv.Atoi(arg)
 	if err != nil {
 		return fmt.Errorf("%w: Item id must be a number", ErrNotNumber)
 	}
-	if err := deleteItem(apiRoot, id, timeout); err != nil {
+	err = withState(stateFile, apiRoot, func(s *listState) error {
+		return deleteItem(apiRoot, id, s, timeout)
+	})
+	if err != nil {
 		return err
 	}
 	return printDel(out, id)
@@ -0,0 +1,81 @@
+/*
+Copyright © 2024 Kazuki Takemoto
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// editCmd represents the edit command
+var editCmd = &cobra.Command{
+	Use:          "edit <id> <task>",
+	Short:        "Replaces the task of an item",
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		apiRoot := viper.GetString("api-root")
+		stateFile := viper.GetString("state-file")
+		timeout := viper.GetDuration("timeout")
+		return editAction(os.Stdout, apiRoot, stateFile, timeout, args)
+	},
+}
+
+func editAction(out io.Writer, apiRoot, stateFile string, timeout time.Duration, args []string) error {
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("%w: Item id must be a number", ErrNotNumber)
+	}
+	task := strings.Join(args[1:], " ")
+	err = withState(stateFile, apiRoot, func(s *listState) error {
+		return editItem(apiRoot, id, task, s, timeout)
+	})
+	if err != nil {
+		return err
+	}
+	return printEdit(out, id, task)
+}
+
+func printEdit(out io.Writer, id int, task string) error {
+	_, err := fmt.Fprintf(out, "Item number %d renamed to %q.\n", id, task)
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// editCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// editCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
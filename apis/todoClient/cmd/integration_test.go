@@ -1,11 +1,16 @@
+//go:build integration
+
 package cmd
 
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -31,6 +36,7 @@ func TestIntegration(t *testing.T) {
 	task := randomTaskName(t)
 	taskId := ""
 	timeout := 1 * time.Second
+	stateFile := filepath.Join(t.TempDir(), "list.json")
 
 	t.Run("AddTask", func(t *testing.T) {
 		args := []string{task}
@@ -46,7 +52,7 @@ func TestIntegration(t *testing.T) {
 	})
 	t.Run("ListTasks", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := listAction(&out, apiRoot, timeout); err != nil {
+		if err := listAction(&out, apiRoot, stateFile, timeout); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 		outList := ""
@@ -87,7 +93,7 @@ func TestIntegration(t *testing.T) {
 	}
 	t.Run("CompleteTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := completeAction(&out, apiRoot, taskId, timeout); err != nil {
+		if err := completeAction(&out, apiRoot, stateFile, taskId, timeout); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 		expOut := fmt.Sprintf("Item number %s marked as completed.\n", taskId)
@@ -97,7 +103,7 @@ func TestIntegration(t *testing.T) {
 	})
 	t.Run("ListCompletedTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := listAction(&out, apiRoot, timeout); err != nil {
+		if err := listAction(&out, apiRoot, stateFile, timeout); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 		outList := ""
@@ -116,9 +122,32 @@ func TestIntegration(t *testing.T) {
 			t.Errorf("Expected status %q, got %q", "X", taskCompleteStatus)
 		}
 	})
+	t.Run("ReopenTask", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := reopenAction(&out, apiRoot, stateFile, taskId, timeout); err != nil {
+			t.Fatalf("Expected no error, got %q.", err)
+		}
+		expOut := fmt.Sprintf("Item number %s reopened.\n", taskId)
+		if expOut != out.String() {
+			t.Fatalf("Expected output %q, got %q", expOut, out.String())
+		}
+	})
+	t.Run("ChangedElsewhere", func(t *testing.T) {
+		// Another client, without the state, completes the task again
+		if err := completeAction(io.Discard, apiRoot, "", taskId, timeout); err != nil {
+			t.Fatalf("Expected no error, got %q.", err)
+		}
+		err := delAction(io.Discard, apiRoot, stateFile, taskId, timeout)
+		if !errors.Is(err, ErrListChanged) {
+			t.Fatalf("Expected error %q, got %q.", ErrListChanged, err)
+		}
+		if err := listAction(io.Discard, apiRoot, stateFile, timeout); err != nil {
+			t.Fatalf("Expected no error, got %q.", err)
+		}
+	})
 	t.Run("DeleteTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := delAction(&out, apiRoot, taskId, timeout); err != nil {
+		if err := delAction(&out, apiRoot, stateFile, taskId, timeout); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 		expOut := fmt.Sprintf("Item number %s deleted.\n", taskId)
@@ -128,7 +157,7 @@ func TestIntegration(t *testing.T) {
 	})
 	t.Run("ListDeletedTask", func(t *testing.T) {
 		var out bytes.Buffer
-		if err := listAction(&out, apiRoot, timeout); err != nil {
+		if err := listAction(&out, apiRoot, stateFile, timeout); err != nil {
 			t.Fatalf("Expected no error, got %q.", err)
 		}
 		scanner := bufio.NewScanner(&out)
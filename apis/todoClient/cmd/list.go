@@ -39,13 +39,19 @@ var listCmd = &cobra.Command{
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		apiRoot := viper.GetString("api-root")
+		stateFile := viper.GetString("state-file")
 		timeout := viper.GetDuration("timeout")
-		return listAction(os.Stdout, apiRoot, timeout)
+		return listAction(os.Stdout, apiRoot, stateFile, timeout)
 	},
 }
 
-func listAction(out io.Writer, apiRoot string, timeout time.Duration) error {
-	items, err := getAll(apiRoot, timeout)
+func listAction(out io.Writer, apiRoot, stateFile string, timeout time.Duration) error {
+	var items []item
+	err := withState(stateFile, apiRoot, func(s *listState) error {
+		var err error
+		items, err = getAll(apiRoot, s, timeout)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -54,12 +60,12 @@ func listAction(out io.Writer, apiRoot string, timeout time.Duration) error {
 
 func printAll(out io.Writer, items []item) error {
 	w := tabwriter.NewWriter(out, 3, 2, 0, ' ', 0)
-	for k, v := range items {
+	for _, v := range items {
 		done := "-"
 		if v.Done {
 			done = "X"
 		}
-		_, err := fmt.Fprintf(w, "%s\t%d\t%s\t\n", done, k+1, v.Task)
+		_, err := fmt.Fprintf(w, "%s\t%d\t%s\t\n", done, v.ID, v.Task)
 		if err != nil {
 			return err
 		}
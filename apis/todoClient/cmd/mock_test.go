@@ -14,12 +14,14 @@ var testResp = map[string]struct {
 		Body: `{
 	"results": [
 		{
+			"ID": 1,
 			"Task": "Task 1",
 			"Done": false,
 			"CreatedAt": "2019-10-28T08:23:38.310097076-04:00",
 			"CompletedAt": "0001-01-01T00:00:00Z"
 		},
 		{
+			"ID": 2,
 			"Task": "Task 2",
 			"Done": false,
 			"CreatedAt": "2019-10-28T08:23:38.323447798-04:00",
@@ -35,6 +37,7 @@ var testResp = map[string]struct {
 		Body: `{
 	"results": [
 		{
+			"ID": 1,
 			"Task": "Task 1",
 			"Done": false,
 			"CreatedAt": "2019-10-28T08:23:38.310097076-04:00",
@@ -65,6 +68,30 @@ var testResp = map[string]struct {
 		Status: http.StatusCreated,
 		Body:   "",
 	},
+	"updated": {
+		Status: http.StatusOK,
+		Body: `{
+	"results": [
+		{
+			"ID": 1,
+			"Task": "Task 1",
+			"Done": false,
+			"CreatedAt": "2019-10-28T08:23:38.310097076-04:00",
+			"CompletedAt": "0001-01-01T00:00:00Z"
+		}
+	],
+	"date": 1572265440,
+	"total_results": 1
+	}`,
+	},
+	"invalid": {
+		Status: http.StatusUnprocessableEntity,
+		Body:   "Unprocessable Entity: task cannot be empty",
+	},
+	"changed": {
+		Status: http.StatusPreconditionFailed,
+		Body:   "Precondition Failed: precondition failed: item 1 changed",
+	},
 	"noContent": {
 		Status: http.StatusNoContent,
 		Body:   "",
@@ -23,6 +23,7 @@ package cmd
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -53,9 +54,21 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.todoClient.yaml)")
 	rootCmd.PersistentFlags().String("api-root", "http://localhost:8080", "Todo API URL")
 	rootCmd.PersistentFlags().DurationP("timeout", "t", 1*time.Second, "Timeout duration")
+	rootCmd.PersistentFlags().String("state-file", defaultStateFile(), "File keeping the last list to detect changes made since (none if empty)")
 	replacer := strings.NewReplacer("-", "_")
 	viper.SetEnvKeyReplacer(replacer)
 	viper.SetEnvPrefix("TODO")
 	viper.BindPFlag("api-root", rootCmd.PersistentFlags().Lookup("api-root"))
 	viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag("state-file", rootCmd.PersistentFlags().Lookup("state-file"))
+}
+
+// defaultStateFile returns the state file in the user cache directory,
+// or no file if there is none
+func defaultStateFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "todoClient", "list.json")
 }
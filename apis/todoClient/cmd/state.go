@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// listState is what the client keeps of the last list it got from the
+// API between runs: the items along with the entity tags of the list
+// and of each item. Changes to an item are only made if it did not
+// change since it was listed, and the list is only sent again by the
+// API if it changed
+type listState struct {
+	APIRoot string
+	ETag    string
+	Items   []item
+	ETags   map[int]string
+}
+
+// loadState reads the state saved in the file name for the API at
+// apiRoot. The state is empty if there is none, if it is for another
+// API or if name is empty, so nothing is kept
+func loadState(name, apiRoot string) (*listState, error) {
+	s := &listState{APIRoot: apiRoot}
+	if name == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	var saved listState
+	if err := json.Unmarshal(data, &saved); err != nil || saved.APIRoot != apiRoot {
+		return s, nil
+	}
+	return &saved, nil
+}
+
+// save saves the state in the file name, unless name is empty
+func (s *listState) save(name string) error {
+	if name == "" {
+		return nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0644)
+}
+
+// changed records the new tag of the item with the given ID after the
+// client changed it, or forgets the item if the tag is empty. The
+// items listed are no longer current, but the tags of the other items
+// still are
+func (s *listState) changed(id int, tag string) {
+	s.ETag, s.Items = "", nil
+	if tag == "" {
+		delete(s.ETags, id)
+		return
+	}
+	if s.ETags == nil {
+		s.ETags = map[int]string{}
+	}
+	s.ETags[id] = tag
+}
+
+// withState runs fn with the state saved in the file name for the API
+// at apiRoot, saving the state back if fn succeeds
+func withState(name, apiRoot string, fn func(s *listState) error) error {
+	s, err := loadState(name, apiRoot)
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	return s.save(name)
+}
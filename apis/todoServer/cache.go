@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"pragprog.com/rggo/interacting/todo"
+	"sync"
+	"time"
+)
+
+// fileVersion identifies the content of the todo file by its time of
+// modification, in nanoseconds, and size. The zero value stands for
+// a missing file
+type fileVersion struct {
+	modTime int64
+	size    int64
+}
+
+// listCache keeps the list of the todo file in memory so requests do
+// not read and parse the file. Changes replace the list with a new
+// one rather than modifying it, so the lists handed to readers never
+// change. The file is checked on every request, and the list is read
+// again when another process modified it.
+//
+// With a write-behind interval, changes are only applied in memory and
+// saved once per interval. They are kept until then and replayed on the
+// latest content of the file, so external edits made in between are
+// kept as well. Changes adding items are saved at once along with the
+// pending ones, so the file reserves the IDs handed out and the pending
+// changes never refer to an ID another process took in the meantime.
+// Without an interval, every change is saved before it returns.
+//
+// Changes are saved with todo.Update, bypassing the journal of the todo
+// command, so they are not in its history and todo undo cannot revert them
+type listCache struct {
+	name     string
+	interval time.Duration
+	opts     []todo.Option
+
+	mu      sync.RWMutex
+	list    todo.List
+	tags    listTags
+	version fileVersion
+	// pending are the changes not saved yet
+	pending []func(*todo.List) error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newListCache returns the cache of the todo file name, saving the
+// changes every interval, or as they are made if interval is zero.
+// opts are used to read and save the file, as in todo.Update.
+// The list is read by the first request
+func newListCache(name string, interval time.Duration, opts ...todo.Option) *listCache {
+	c := &listCache{
+		name:     name,
+		interval: interval,
+		opts:     opts,
+		// No file has a negative size, so the first request reads it
+		version: fileVersion{size: -1},
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if interval <= 0 {
+		close(c.done)
+		return c
+	}
+	go c.writeBehind()
+	return c
+}
+
+// List returns the list, reading the file again if it changed. The
+// list is shared with the other requests and must not be modified
+func (c *listCache) List() (todo.List, error) {
+	l, _, err := c.Tagged()
+	return l, err
+}
+
+// Tagged returns the list along with its entity tags
+func (c *listCache) Tagged() (todo.List, listTags, error) {
+	c.mu.RLock()
+	l, t, stale := c.list, c.tags, c.stat() != c.version
+	c.mu.RUnlock()
+	if !stale {
+		return l, t, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.refresh(); err != nil {
+		return todo.List{}, listTags{}, err
+	}
+	return c.list, c.tags, nil
+}
+
+// Update applies fn to a copy of the list and makes it the list. The
+// list is saved before Update returns unless the cache writes behind.
+// Nothing changes if fn returns an error
+func (c *listCache) Update(fn func(*todo.List) error) error {
+	return c.UpdateIf(nil, fn)
+}
+
+// UpdateIf is Update, changing the list only if check accepts the tags
+// of its current content. Unlike fn, check is not run again when the
+// pending changes are replayed
+func (c *listCache) UpdateIf(check func(listTags) error, fn func(*todo.List) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.refresh(); err != nil {
+		return err
+	}
+	if check != nil {
+		if err := check(c.tags); err != nil {
+			return err
+		}
+	}
+
+	if c.interval <= 0 {
+		return c.save(fn)
+	}
+
+	l := c.list.Clone()
+	if err := fn(&l); err != nil {
+		return err
+	}
+	if l.LastID != c.list.LastID {
+		return c.save(fn)
+	}
+	c.setList(l)
+	c.pending = append(c.pending, fn)
+	return nil
+}
+
+// Flush saves the pending changes, applying them to the latest
+// content of the file
+func (c *listCache) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pending) == 0 {
+		return nil
+	}
+	return c.save(nil)
+}
+
+// save replays the pending changes and fn, unless it is nil, on the
+// latest content of the file, saves it and makes it the list.
+// The version kept is the one read while the file is locked, so the
+// next request reads the file saved again rather than miss a change
+// made by another process right after the save.
+// It is called with the write lock held
+func (c *listCache) save(fn func(*todo.List) error) error {
+	var (
+		saved todo.List
+		v     fileVersion
+	)
+	err := todo.Update(c.name, func(l *todo.List) error {
+		v = c.stat()
+		c.replay(l)
+		if fn != nil {
+			if err := fn(l); err != nil {
+				return err
+			}
+		}
+		saved = *l
+		return nil
+	}, c.opts...)
+	if err != nil {
+		return err
+	}
+	c.setList(saved)
+	c.version, c.pending = v, nil
+	return nil
+}
+
+// Close stops writing behind and saves the pending changes
+func (c *listCache) Close() error {
+	select {
+	case <-c.stop:
+	default:
+		close(c.stop)
+	}
+	<-c.done
+	return c.Flush()
+}
+
+// writeBehind saves the pending changes every interval until Close
+func (c *listCache) writeBehind() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Flush(); err != nil {
+				log.Printf("Unable to save %s: %s", c.name, err)
+			}
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// refresh reads the file again if it changed since it was last read
+// or saved, replaying the pending changes on its content.
+// It is called with the write lock held
+func (c *listCache) refresh() error {
+	v := c.stat()
+	if v == c.version {
+		return nil
+	}
+	l := todo.List{}
+	if err := l.Get(c.name, c.opts...); err != nil {
+		return err
+	}
+	c.replay(&l)
+	c.setList(l)
+	c.version = v
+	return nil
+}
+
+// setList makes l the list along with its tags.
+// It is called with the write lock held
+func (c *listCache) setList(l todo.List) {
+	c.list, c.tags = l, newListTags(l)
+}
+
+// replay applies the pending changes to l. Changes that no longer
+// apply, such as completing an item deleted by another process, are
+// dropped
+func (c *listCache) replay(l *todo.List) {
+	kept := c.pending[:0]
+	for _, fn := range c.pending {
+		if err := fn(l); err != nil {
+			log.Printf("Dropping a change to %s made before it was edited: %s", c.name, err)
+			continue
+		}
+		kept = append(kept, fn)
+	}
+	c.pending = kept
+}
+
+// Workflow returns the workflow set with the todo command in the
+// catalog next to the file, or todo.DefaultWorkflow without one.
+// The catalog is read on every call, as it seldom matters
+func (c *listCache) Workflow() (todo.Workflow, error) {
+	cat := todo.Catalog{}
+	if err := cat.Get(c.name+".lists", c.opts...); err != nil {
+		return nil, err
+	}
+	return cat.Statuses(), nil
+}
+
+// stat returns the current version of the file
+func (c *listCache) stat() fileVersion {
+	info, err := os.Stat(c.name)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Unable to check %s for changes: %s", c.name, err)
+		}
+		return fileVersion{}
+	}
+	return fileVersion{modTime: info.ModTime().UnixNano(), size: info.Size()}
+}
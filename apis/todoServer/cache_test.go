@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"pragprog.com/rggo/interacting/todo"
+	"sync"
+	"testing"
+	"time"
+)
+
+// editFile changes the todo file as another process would, moving its
+// time of modification forward so the change is seen at once
+func editFile(t testing.TB, name string, fn func(*todo.List) error) {
+	t.Helper()
+	if err := todo.Update(name, fn); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(name, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tasks returns the tasks of the list
+func tasks(l todo.List) []string {
+	var ts []string
+	for _, item := range l.Items {
+		ts = append(ts, item.Task)
+	}
+	return ts
+}
+
+func TestCacheExternalChange(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "todotest")
+	c := newListCache(name, 0)
+	l, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Items) != 0 {
+		t.Fatalf("Expected an empty list, got %v.", tasks(l))
+	}
+	if err := c.Update(func(l *todo.List) error { l.Add("Ours"); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	editFile(t, name, func(l *todo.List) error { l.Add("Theirs"); return nil })
+	if l, err = c.List(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(tasks(l)) != "[Ours Theirs]" {
+		t.Errorf("Expected the external change to be read, got %v.", tasks(l))
+	}
+}
+
+func TestCacheEncrypted(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "todotest")
+	opt := todo.WithPassphrase("secret")
+	l := todo.List{}
+	l.Add("Secret task")
+	if err := l.Save(name, opt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newListCache(name, 0).List(); !errors.Is(err, todo.ErrPassphraseRequired) {
+		t.Fatalf("Expected error %q, got %q.", todo.ErrPassphraseRequired, err)
+	}
+
+	c := newListCache(name, 0, opt)
+	if err := c.Update(func(l *todo.List) error { l.Add("Another task"); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if l, err := c.List(); err != nil || fmt.Sprint(tasks(l)) != "[Secret task Another task]" {
+		t.Fatalf("Expected both tasks, got %v, %v.", tasks(l), err)
+	}
+	if encrypted, err := todo.IsEncrypted(name); err != nil || !encrypted {
+		t.Errorf("Expected the file to stay encrypted, got %v, %v.", encrypted, err)
+	}
+}
+
+func TestCacheWriteBehind(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "todotest")
+	editFile(t, name, func(l *todo.List) error {
+		l.Add("Task number 1.")
+		l.Add("Task number 2.")
+		return nil
+	})
+	// The interval is long enough for the test to flush the changes
+	c := newListCache(name, time.Hour)
+	defer c.Close()
+
+	saved := func(t *testing.T) []string {
+		t.Helper()
+		l := todo.List{}
+		if err := l.Get(name); err != nil {
+			t.Fatal(err)
+		}
+		return tasks(l)
+	}
+	t.Run("Pending", func(t *testing.T) {
+		if err := c.Update(func(l *todo.List) error { return l.Edit(1, "Ours") }); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Update(func(l *todo.List) error { return l.Complete(2) }); err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(saved(t)); got != "[Task number 1. Task number 2.]" {
+			t.Errorf("Expected the changes to wait for the interval, got %v saved.", got)
+		}
+		if err := c.Update(func(l *todo.List) error { return l.Complete(10) }); err == nil {
+			t.Error("Expected an invalid change to fail.")
+		}
+	})
+	t.Run("ExternalChange", func(t *testing.T) {
+		editFile(t, name, func(l *todo.List) error {
+			l.Add("Theirs")
+			return l.Delete(2)
+		})
+		l, err := c.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(tasks(l)) != "[Ours Theirs]" {
+			t.Errorf("Expected the pending changes to be replayed, got %v.", tasks(l))
+		}
+	})
+	t.Run("Add", func(t *testing.T) {
+		if err := c.Update(func(l *todo.List) error { l.Add("Added"); return nil }); err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(saved(t)); got != "[Ours Theirs Added]" {
+			t.Errorf("Expected the changes to be saved with an added item, got %v saved.", got)
+		}
+	})
+	t.Run("Flush", func(t *testing.T) {
+		if err := c.Update(func(l *todo.List) error { return l.Complete(1) }); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		l := todo.List{}
+		if err := l.Get(name); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(tasks(l)) != "[Ours Theirs Added]" || !l.Items[0].Done() {
+			t.Errorf("Expected the pending changes to be saved, got %v.", tasks(l))
+		}
+	})
+}
+
+func TestCacheWriteBehindExternalAdd(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "todotest")
+	editFile(t, name, func(l *todo.List) error { l.Add("Task"); return nil })
+	c := newListCache(name, time.Hour)
+	defer c.Close()
+
+	if err := c.Update(func(l *todo.List) error { l.Add("Ours"); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	editFile(t, name, func(l *todo.List) error { l.Add("Theirs"); return nil })
+	// The cache handed out ID 2 for the item it added, so the pending
+	// change must complete that item rather than the external one
+	if err := c.Update(func(l *todo.List) error { return l.Complete(2) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := todo.List{}
+	if err := l.Get(name); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(tasks(l)) != "[Task Ours Theirs]" {
+		t.Fatalf("Expected the items in the order they were added, got %v.", tasks(l))
+	}
+	if !l.Items[1].Done() || l.Items[2].Done() {
+		t.Errorf("Expected only the item added by the cache to be completed, got %v.", l.String())
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "todotest")
+	c := newListCache(name, 10*time.Millisecond)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.Update(func(l *todo.List) error { l.Add("Task"); return nil })
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := c.List(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := todo.List{}
+	if err := l.Get(name); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Items) != 10 {
+		t.Errorf("Expected 10 items saved, got %d.", len(l.Items))
+	}
+}
+
+// reloadRouter serves GET /todo reading the file on every request
+// under one lock, as the server did before caching the list
+func reloadRouter(todoFile string) http.Handler {
+	var mu sync.Mutex
+	return http.StripPrefix("/todo", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		list := &todo.List{}
+		if err := list.Get(todoFile); err != nil {
+			replyError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		getAllHandler(w, r, list, newListTags(*list))
+	}))
+}
+
+func BenchmarkGetAll(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		name := filepath.Join(b.TempDir(), "todotest")
+		editFile(b, name, func(l *todo.List) error {
+			for i := 0; i < n; i++ {
+				l.Add(fmt.Sprintf("Task number %d.", i))
+			}
+			return nil
+		})
+		handlers := []struct {
+			name string
+			h    http.Handler
+		}{
+			{"Reload", reloadRouter(name)},
+			{"Cached", newMux(newListCache(name, 0))},
+		}
+		for _, h := range handlers {
+			b.Run(fmt.Sprintf("%s/%d", h.name, n), func(b *testing.B) {
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						w := httptest.NewRecorder()
+						h.h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todo", nil))
+						if w.Code != http.StatusOK {
+							b.Fatalf("Expected %q, got %q.", http.StatusText(http.StatusOK), http.StatusText(w.Code))
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+// BenchmarkEdit measures edits, which are written behind, unlike
+// adds that are always saved at once
+func BenchmarkEdit(b *testing.B) {
+	for _, interval := range []time.Duration{0, time.Second} {
+		name := filepath.Join(b.TempDir(), "todotest")
+		editFile(b, name, func(l *todo.List) error { l.Add("Task"); return nil })
+		c := newListCache(name, interval)
+		h := newMux(c)
+		b.Run(fmt.Sprintf("WriteBehind=%s", interval), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				body := bytes.NewBufferString(fmt.Sprintf(`{"Task": "Task %d"}`, i))
+				r := httptest.NewRequest(http.MethodPatch, "/todo/1", body)
+				r.Header.Set("Content-Type", "application/merge-patch+json")
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				if w.Code != http.StatusOK {
+					b.Fatalf("Expected %q, got %q.", http.StatusText(http.StatusOK), http.StatusText(w.Code))
+				}
+			}
+		})
+		if err := c.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCacheUpdateIf(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "todotest")
+	editFile(t, name, func(l *todo.List) error { l.Add("Task"); return nil })
+	c := newListCache(name, time.Hour)
+	defer c.Close()
+	_, tags, err := c.Tagged()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(t listTags) error {
+		if t.list != tags.list {
+			return ErrPrecondition
+		}
+		return nil
+	}
+	if err := c.UpdateIf(check, func(l *todo.List) error { return l.Edit(1, "Ours") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdateIf(check, func(l *todo.List) error { return l.Edit(1, "Stale") }); err == nil {
+		t.Error("Expected a change to a stale list to fail.")
+	}
+	// The check is not run again when the pending change is replayed
+	editFile(t, name, func(l *todo.List) error { l.Add("Theirs"); return nil })
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l := todo.List{}
+	if err := l.Get(name); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(tasks(l)) != "[Ours Theirs]" {
+		t.Errorf("Expected the checked change to be saved, got %v.", tasks(l))
+	}
+}
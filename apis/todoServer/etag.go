@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pragprog.com/rggo/interacting/todo"
+	"strings"
+)
+
+// listTags are the entity tags of a list and of its items, by ID.
+// They are computed from the content of the items, so they only
+// change when the items do, even across restarts
+type listTags struct {
+	list  string
+	items map[int]string
+}
+
+// newListTags returns the entity tags of the list. The tag of the
+// list is derived from the tags of its items, in order
+func newListTags(l todo.List) listTags {
+	t := listTags{items: make(map[int]string, len(l.Items))}
+	h := sha256.New()
+	for _, item := range l.Items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			// Items always encode, but an empty tag never matches
+			continue
+		}
+		tag := hashTag(data)
+		t.items[item.ID] = tag
+		h.Write([]byte(tag))
+	}
+	t.list = fmt.Sprintf("%q", hex.EncodeToString(h.Sum(nil)[:16]))
+	return t
+}
+
+// of returns the tags of the items of l, by ID
+func (t listTags) of(l todo.List) map[int]string {
+	tags := make(map[int]string, len(l.Items))
+	for _, item := range l.Items {
+		tags[item.ID] = t.items[item.ID]
+	}
+	return tags
+}
+
+// hashTag returns a strong entity tag for data
+func hashTag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%q", hex.EncodeToString(sum[:16]))
+}
+
+// matchTag reports whether the value of an If-Match or If-None-Match
+// header lists tag. "*" matches any tag. As in RFC 9110, weak tags
+// only match with the weak comparison used by If-None-Match
+func matchTag(header, tag string, weak bool) bool {
+	if tag == "" {
+		return false
+	}
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" {
+			return true
+		}
+		if weak {
+			t = strings.TrimPrefix(t, "W/")
+		}
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// setETag sets the ETag header of the reply, unless tag is empty
+func setETag(w http.ResponseWriter, tag string) {
+	if tag != "" {
+		w.Header().Set("ETag", tag)
+	}
+}
+
+// notModified replies with 304 Not Modified and returns true if the
+// If-None-Match header of a GET request lists the tag
+func notModified(w http.ResponseWriter, r *http.Request, tag string) bool {
+	h := r.Header.Get("If-None-Match")
+	if r.Method != http.MethodGet || h == "" || !matchTag(h, tag, true) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// ifMatch returns the precondition set by the If-Match header of a
+// request changing the item with the given ID, or nil without one.
+// The header must list the current tag of the item
+func ifMatch(r *http.Request, id int) func(listTags) error {
+	h := r.Header.Get("If-Match")
+	if h == "" {
+		return nil
+	}
+	return func(t listTags) error {
+		if !matchTag(h, t.items[id], false) {
+			return fmt.Errorf("%w: item %d changed", ErrPrecondition, id)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"pragprog.com/rggo/interacting/todo"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sortField compares the items of a list at positions i and j by
+// a field, and tells whether the item at i has no value for it
+type sortField struct {
+	compare func(l todo.List, i, j int) int
+	empty   func(l todo.List, i int) bool
+}
+
+// sortFields are the fields accepted by the sort parameter
+var sortFields = map[string]sortField{
+	"id": {
+		compare: func(l todo.List, i, j int) int { return cmp.Compare(l.Items[i].ID, l.Items[j].ID) },
+	},
+	"task": {
+		compare: func(l todo.List, i, j int) int { return strings.Compare(l.Items[i].Task, l.Items[j].Task) },
+	},
+	"created": {
+		compare: func(l todo.List, i, j int) int { return l.Items[i].CreatedAt.Compare(l.Items[j].CreatedAt) },
+	},
+	"completed": {
+		compare: func(l todo.List, i, j int) int { return l.Items[i].CompletedAt.Compare(l.Items[j].CompletedAt) },
+		empty:   func(l todo.List, i int) bool { return l.Items[i].CompletedAt.IsZero() },
+	},
+	"due": {
+		compare: func(l todo.List, i, j int) int { return l.Items[i].Due.Compare(l.Items[j].Due) },
+		empty:   func(l todo.List, i int) bool { return l.Items[i].Due.IsZero() },
+	},
+	"priority": {
+		compare: func(l todo.List, i, j int) int { return strings.Compare(l.Items[i].Priority, l.Items[j].Priority) },
+		empty:   func(l todo.List, i int) bool { return l.Items[i].Priority == "" },
+	},
+}
+
+// listParams are the query parameters of GET /todo:
+//
+//	q=expression          items matching a todo filter expression
+//	done=true|false       completed or open items
+//	search=text           tasks containing the text, ignoring case
+//	created_after=date    also created_before, completed_after and
+//	                      completed_before, with dates such as
+//	                      2024-01-31, yesterday or "2 weeks ago"
+//	sort=field            id, task, created, completed, due or priority
+//	order=asc|desc        ascending by default
+//	limit=n, offset=n     the page of n items starting at offset
+//
+// After includes the given time while before excludes it, so
+// created_after=monday&created_before=tuesday is the items created
+// on monday
+type listParams struct {
+	query         *todo.Query
+	done          *bool
+	search        string
+	created       [2]time.Time
+	completed     [2]time.Time
+	sort          string
+	desc          bool
+	limit, offset int
+}
+
+// parseListParams parses the query parameters of GET /todo
+func parseListParams(v url.Values, now time.Time) (listParams, error) {
+	p := listParams{sort: v.Get("sort"), search: strings.ToLower(v.Get("search"))}
+	if expr := v.Get("q"); expr != "" {
+		q, err := todo.ParseQuery(expr, now)
+		if err != nil {
+			return p, fmt.Errorf("%w: %s", ErrInvalidData, err)
+		}
+		p.query = q
+	}
+	if s := v.Get("done"); s != "" {
+		done, err := strconv.ParseBool(s)
+		if err != nil {
+			return p, fmt.Errorf("%w: invalid done %q", ErrInvalidData, s)
+		}
+		p.done = &done
+	}
+	dates := []struct {
+		name string
+		t    *time.Time
+	}{
+		{"created_after", &p.created[0]},
+		{"created_before", &p.created[1]},
+		{"completed_after", &p.completed[0]},
+		{"completed_before", &p.completed[1]},
+	}
+	for _, d := range dates {
+		if s := v.Get(d.name); s != "" {
+			t, _, err := todo.ParseDate(s, now)
+			if err != nil {
+				return p, fmt.Errorf("%w: invalid %s: %s", ErrInvalidData, d.name, err)
+			}
+			*d.t = t
+		}
+	}
+	if _, ok := sortFields[p.sort]; p.sort != "" && !ok {
+		return p, fmt.Errorf("%w: invalid sort %q", ErrInvalidData, p.sort)
+	}
+	switch order := v.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		p.desc = true
+	default:
+		return p, fmt.Errorf("%w: invalid order %q", ErrInvalidData, order)
+	}
+	for _, n := range []struct {
+		name string
+		v    *int
+	}{{"limit", &p.limit}, {"offset", &p.offset}} {
+		if s := v.Get(n.name); s != "" {
+			i, err := strconv.Atoi(s)
+			if err != nil || i < 0 {
+				return p, fmt.Errorf("%w: invalid %s %q", ErrInvalidData, n.name, s)
+			}
+			*n.v = i
+		}
+	}
+	return p, nil
+}
+
+// match reports whether the item satisfies the filters
+func (p listParams) match(l todo.List, i int) bool {
+	item := l.Items[i]
+	switch {
+	case p.query != nil && !p.query.Match(item):
+		return false
+	case p.done != nil && item.Done() != *p.done:
+		return false
+	case p.search != "" && !strings.Contains(strings.ToLower(item.Task), p.search):
+		return false
+	}
+	return inRange(item.CreatedAt, p.created) && inRange(item.CompletedAt, p.completed)
+}
+
+// inRange reports whether t is within the range, whose zero bounds
+// are open. Zero times are only within an open range
+func inRange(t time.Time, r [2]time.Time) bool {
+	if r[0].IsZero() && r[1].IsZero() {
+		return true
+	}
+	return !t.IsZero() && !t.Before(r[0]) && (r[1].IsZero() || t.Before(r[1]))
+}
+
+// apply returns the page of the items of l matching the filters, in
+// the requested order, along with the number of matching items. Items
+// without a value for the sort field come last in both orders.
+// l is left untouched
+func (p listParams) apply(l todo.List) (todo.List, int) {
+	var indexes []int
+	for i := range l.Items {
+		if p.match(l, i) {
+			indexes = append(indexes, i)
+		}
+	}
+	if f, ok := sortFields[p.sort]; ok {
+		slices.SortStableFunc(indexes, func(i, j int) int {
+			if f.empty != nil && f.empty(l, i) != f.empty(l, j) {
+				if f.empty(l, i) {
+					return 1
+				}
+				return -1
+			}
+			if p.desc {
+				return f.compare(l, j, i)
+			}
+			return f.compare(l, i, j)
+		})
+	}
+	total := len(indexes)
+	start, end := min(p.offset, total), total
+	// Comparing with the items left keeps huge limits from overflowing
+	if p.limit > 0 && p.limit < total-start {
+		end = start + p.limit
+	}
+	page := todo.List{}
+	for _, i := range indexes[start:end] {
+		page.Items = append(page.Items, l.Items[i])
+	}
+	return page, total
+}
+
+// links returns the Link header pointing at the first, previous, next
+// and last pages of the matching items, or "" when there is no limit
+func (p listParams) links(v url.Values, matched int) string {
+	if p.limit == 0 {
+		return ""
+	}
+	link := func(offset int, rel string) string {
+		q := url.Values{}
+		for k, vs := range v {
+			q[k] = vs
+		}
+		q.Set("offset", strconv.Itoa(offset))
+		return fmt.Sprintf(`</todo?%s>; rel="%s"`, q.Encode(), rel)
+	}
+	last := 0
+	if matched > 0 {
+		last = (matched - 1) / p.limit * p.limit
+	}
+	links := []string{link(0, "first")}
+	if p.offset > 0 {
+		links = append(links, link(min(max(0, p.offset-p.limit), last), "prev"))
+	}
+	if p.offset < matched && p.limit < matched-p.offset {
+		links = append(links, link(p.offset+p.limit, "next"))
+	}
+	links = append(links, link(last, "last"))
+	return strings.Join(links, ", ")
+}
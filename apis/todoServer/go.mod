@@ -4,4 +4,6 @@ go 1.21.2
 
 require pragprog.com/rggo/interacting/todo v0.0.0
 
+require golang.org/x/crypto v0.16.0 // indirect
+
 replace pragprog.com/rggo/interacting/todo => ../../interacting/todo
@@ -4,17 +4,36 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"pragprog.com/rggo/interacting/todo"
 	"strconv"
-	"sync"
+	"time"
 )
 
 var (
-	ErrNotFound    = errors.New("not found")
-	ErrInvalidData = errors.New("invalid data")
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidData  = errors.New("invalid data")
+	ErrPrecondition = errors.New("precondition failed")
 )
 
+// validationErrors are the errors of updates with invalid items
+var validationErrors = []error{
+	todo.ErrInvalidItem,
+	todo.ErrEmptyTask,
+	todo.ErrInvalidPriority,
+	todo.ErrInvalidParent,
+	todo.ErrInvalidDep,
+	todo.ErrDependencyCycle,
+	todo.ErrInvalidListName,
+	todo.ErrInvalidStatus,
+	todo.ErrInvalidRecurrence,
+}
+
+// maxBodySize is the largest request body accepted
+const maxBodySize = 1 << 20
+
 func rootHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		replyError(w, r, http.StatusNotFound, "")
@@ -24,28 +43,26 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
 	replyTextContent(w, r, http.StatusOK, content)
 }
 
-func todoRouter(todoFile string, l sync.Locker) http.HandlerFunc {
+func todoRouter(c *listCache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		list := &todo.List{}
-		l.Lock()
-		defer l.Unlock()
-		if err := list.Get(todoFile); err != nil {
+		list, tags, err := c.Tagged()
+		if err != nil {
 			replyError(w, r, http.StatusInternalServerError, err.Error())
 			return
 		}
 		if r.URL.Path == "" {
 			switch r.Method {
 			case http.MethodGet:
-				getAllHandler(w, r, list)
+				getAllHandler(w, r, &list, tags)
 			case http.MethodPost:
-				addHandler(w, r, list, todoFile)
+				addHandler(w, r, c)
 			default:
 				message := "Method not supported"
 				replyError(w, r, http.StatusMethodNotAllowed, message)
 			}
 			return
 		}
-		id, err := validateID(r.URL.Path, list)
+		id, i, err := validateID(r.URL.Path, &list)
 		if err != nil {
 			if errors.Is(err, ErrNotFound) {
 				replyError(w, r, http.StatusNotFound, err.Error())
@@ -56,11 +73,13 @@ func todoRouter(todoFile string, l sync.Locker) http.HandlerFunc {
 		}
 		switch r.Method {
 		case http.MethodGet:
-			getOneHandler(w, r, list, id)
+			getOneHandler(w, r, &list, i, tags)
 		case http.MethodDelete:
-			deleteHandler(w, r, list, id, todoFile)
+			deleteHandler(w, r, id, c)
 		case http.MethodPatch:
-			patchHandler(w, r, list, id, todoFile)
+			patchHandler(w, r, id, c)
+		case http.MethodPut:
+			putHandler(w, r, id, c)
 		default:
 			message := "Method not supported"
 			replyError(w, r, http.StatusMethodNotAllowed, message)
@@ -69,45 +88,174 @@ func todoRouter(todoFile string, l sync.Locker) http.HandlerFunc {
 	}
 }
 
-func getAllHandler(w http.ResponseWriter, r *http.Request, list *todo.List) {
+// getAllHandler replies with the items, tagged with the entity tag of
+// the whole list, or with 304 Not Modified if the client has it
+func getAllHandler(w http.ResponseWriter, r *http.Request, list *todo.List, tags listTags) {
+	p, err := parseListParams(r.URL.Query(), time.Now())
+	if err != nil {
+		replyError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	setETag(w, tags.list)
+	if notModified(w, r, tags.list) {
+		return
+	}
+	results, matched := p.apply(*list)
+	if links := p.links(r.URL.Query(), matched); links != "" {
+		w.Header().Set("Link", links)
+	}
 	resp := &todoResponse{
-		Results: *list,
+		Results:      results,
+		TotalResults: len(list.Items),
+		ETags:        tags.of(results),
 	}
 	replyJSONContent(w, r, http.StatusOK, resp)
 }
 
-func getOneHandler(w http.ResponseWriter, r *http.Request, list *todo.List, id int) {
+// getOneHandler replies with the item at position i, tagged with its
+// entity tag, or with 304 Not Modified if the client has it
+func getOneHandler(w http.ResponseWriter, r *http.Request, list *todo.List, i int, tags listTags) {
+	tag := tags.items[list.Items[i].ID]
+	setETag(w, tag)
+	if notModified(w, r, tag) {
+		return
+	}
+	results := todo.List{Items: list.Items[i : i+1]}
 	resp := &todoResponse{
-		Results: (*list)[id-1 : id],
+		Results:      results,
+		TotalResults: 1,
+		ETags:        tags.of(results),
 	}
 	replyJSONContent(w, r, http.StatusOK, resp)
 }
 
-func deleteHandler(w http.ResponseWriter, r *http.Request, list *todo.List, id int, todoFile string) {
-	list.Delete(id)
-	if err := list.Save(todoFile); err != nil {
-		replyError(w, r, http.StatusInternalServerError, err.Error())
+func deleteHandler(w http.ResponseWriter, r *http.Request, id int, c *listCache) {
+	err := c.UpdateIf(ifMatch(r, id), func(l *todo.List) error {
+		return l.Delete(id)
+	})
+	if err != nil {
+		replyUpdateError(w, r, err)
 		return
 	}
 	replyTextContent(w, r, http.StatusNoContent, "")
 }
 
-func patchHandler(w http.ResponseWriter, r *http.Request, list *todo.List, id int, todoFile string) {
+// patchHandler completes the item with the complete query param, or
+// applies the JSON Merge Patch in the body to it, replying with the
+// updated item
+func patchHandler(w http.ResponseWriter, r *http.Request, id int, c *listCache) {
 	q := r.URL.Query()
-	if _, ok := q["complete"]; !ok {
-		message := "Missing query param 'complete'"
-		replyError(w, r, http.StatusBadRequest, message)
+	if _, ok := q["complete"]; ok {
+		err := c.UpdateIf(ifMatch(r, id), func(l *todo.List) error {
+			return l.Complete(id)
+		})
+		if err != nil {
+			replyUpdateError(w, r, err)
+			return
+		}
+		if _, tags, err := c.Tagged(); err == nil {
+			setETag(w, tags.items[id])
+		}
+		replyTextContent(w, r, http.StatusNoContent, "")
 		return
 	}
-	list.Complete(id)
-	if err := list.Save(todoFile); err != nil {
+	patch, ok := readObject(w, r, "application/merge-patch+json")
+	if !ok {
+		return
+	}
+	wf, err := c.Workflow()
+	if err != nil {
 		replyError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
-	replyTextContent(w, r, http.StatusNoContent, "")
+	update := func(l *todo.List) error {
+		i, err := l.Index(id)
+		if err != nil {
+			return err
+		}
+		item, err := json.Marshal(l.Items[i])
+		if err != nil {
+			return err
+		}
+		data, err := patchItem(item, patch)
+		if err != nil {
+			return err
+		}
+		return l.Replace(id, data, wf)
+	}
+	if err := c.UpdateIf(ifMatch(r, id), update); err != nil {
+		replyUpdateError(w, r, err)
+		return
+	}
+	replyItem(w, r, id, c)
 }
 
-func addHandler(w http.ResponseWriter, r *http.Request, list *todo.List, todoFile string) {
+// putHandler replaces the item with the one in the body,
+// replying with the updated item
+func putHandler(w http.ResponseWriter, r *http.Request, id int, c *listCache) {
+	obj, ok := readObject(w, r, "application/json")
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	wf, err := c.Workflow()
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	err = c.UpdateIf(ifMatch(r, id), func(l *todo.List) error {
+		return l.Replace(id, data, wf)
+	})
+	if err != nil {
+		replyUpdateError(w, r, err)
+		return
+	}
+	replyItem(w, r, id, c)
+}
+
+// readObject reads the JSON object in the body of a request sent with
+// the content type, or with application/json. It replies with an error
+// and returns false if the body is not a JSON object
+func readObject(w http.ResponseWriter, r *http.Request, contentType string) (map[string]any, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != contentType && mediaType != "application/json" {
+		message := fmt.Sprintf("Content-Type must be %s", contentType)
+		replyError(w, r, http.StatusUnsupportedMediaType, message)
+		return nil, false
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		replyError(w, r, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	obj, err := decodeObject(body)
+	if err != nil {
+		replyError(w, r, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return obj, true
+}
+
+// replyItem replies with the item with the given ID
+func replyItem(w http.ResponseWriter, r *http.Request, id int, c *listCache) {
+	list, tags, err := c.Tagged()
+	if err != nil {
+		replyError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	i, err := list.Index(id)
+	if err != nil {
+		replyError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	getOneHandler(w, r, &list, i, tags)
+}
+
+func addHandler(w http.ResponseWriter, r *http.Request, c *listCache) {
 	item := struct {
 		Task string `json:"task"`
 	}{}
@@ -116,24 +264,52 @@ func addHandler(w http.ResponseWriter, r *http.Request, list *todo.List, todoFil
 		replyError(w, r, http.StatusBadRequest, message)
 		return
 	}
-	list.Add(item.Task)
-	if err := list.Save(todoFile); err != nil {
-		replyError(w, r, http.StatusInternalServerError, err.Error())
+	err := c.Update(func(l *todo.List) error {
+		l.Add(item.Task)
+		return nil
+	})
+	if err != nil {
+		replyUpdateError(w, r, err)
 		return
 	}
 	replyTextContent(w, r, http.StatusCreated, "")
 }
 
-func validateID(path string, list *todo.List) (int, error) {
+// replyUpdateError replies with the status matching an error returned by an update
+func replyUpdateError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, todo.ErrNotExists):
+		replyError(w, r, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, todo.ErrBlocked):
+		replyError(w, r, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, ErrPrecondition):
+		replyError(w, r, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+	for _, invalid := range validationErrors {
+		if errors.Is(err, invalid) {
+			replyError(w, r, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+	}
+	replyError(w, r, http.StatusInternalServerError, err.Error())
+}
+
+// validateID parses the item ID from the path and returns it
+// along with the position of the matching item in the list
+func validateID(path string, list *todo.List) (int, int, error) {
 	id, err := strconv.Atoi(path)
 	if err != nil {
-		return 0, fmt.Errorf("%w: Invalid ID: %s", ErrInvalidData, err)
+		return 0, -1, fmt.Errorf("%w: Invalid ID: %s", ErrInvalidData, err)
 	}
 	if id < 1 {
-		return 0, fmt.Errorf("%w, Invalid ID: Less than one", ErrInvalidData)
+		return 0, -1, fmt.Errorf("%w, Invalid ID: Less than one", ErrInvalidData)
 	}
-	if id > len(*list) {
-		return id, fmt.Errorf("%w: ID %d not found", ErrNotFound, id)
+	i, err := list.Index(id)
+	if err != nil {
+		return id, -1, fmt.Errorf("%w: ID %d not found", ErrNotFound, id)
 	}
-	return id, nil
+	return id, i, nil
 }
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"pragprog.com/rggo/interacting/todo"
+	"syscall"
 	"time"
 )
 
@@ -12,14 +17,38 @@ func main() {
 	host := flag.String("h", "localhost", "Server host")
 	port := flag.Int("p", 8080, "Server port")
 	todoFile := flag.String("f", "todoServer.json", "todo JSON file")
+	writeBehind := flag.Duration("w", 0, "Save the changes at this interval, i.e. 5s, rather than as they are made. Adds are always saved at once")
 	flag.Parse()
+	// An encrypted file takes the passphrase of the todo command
+	c := newListCache(*todoFile, *writeBehind, todo.WithPassphrase(os.Getenv("TODO_PASSPHRASE")))
+	// Read the file up front, so a missing or wrong passphrase
+	// stops the server rather than failing every request
+	if _, err := c.List(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, todo.ErrPassphraseRequired) || errors.Is(err, todo.ErrDecrypt) {
+			fmt.Fprintln(os.Stderr, "Set TODO_PASSPHRASE to the passphrase of the file")
+		}
+		os.Exit(1)
+	}
 	s := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", *host, *port),
-		Handler:      newMux(*todoFile),
+		Handler:      newMux(c),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
-	if err := s.ListenAndServe(); err != nil {
+
+	// Save the changes written behind before exiting on a signal
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		s.Shutdown(context.Background())
+	}()
+	if err := s.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := c.Close(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
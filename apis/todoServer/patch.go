@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// mergePatch applies the JSON Merge Patch patch to target, as defined
+// by RFC 7396: objects are merged recursively, null removes a member
+// and any other value replaces it. Members are matched ignoring case,
+// like encoding/json does when decoding
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if key, found := findKey(t, k); found {
+			k = key
+		}
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
+
+// findKey returns the key of m equal to k ignoring case
+func findKey(m map[string]any, k string) (string, bool) {
+	if _, ok := m[k]; ok {
+		return k, true
+	}
+	for key := range m {
+		if strings.EqualFold(key, k) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// patchItem applies the merge patch to the JSON encoding of an item.
+// Items are encoded with both their status and whether they are done,
+// and the status wins when decoding, so a patch changing Done alone
+// drops the status for it to follow Done
+func patchItem(item []byte, patch map[string]any) ([]byte, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(item, &doc); err != nil {
+		return nil, err
+	}
+	done, patchesDone := patch[mustKey(patch, "Done")]
+	_, patchesStatus := patch[mustKey(patch, "Status")]
+	if patchesDone && !patchesStatus && done != doc["Done"] {
+		delete(doc, "Status")
+	}
+	return json.Marshal(mergePatch(doc, patch))
+}
+
+// mustKey returns the key of m equal to k ignoring case, or k
+func mustKey(m map[string]any, k string) string {
+	if key, ok := findKey(m, k); ok {
+		return key
+	}
+	return k
+}
+
+// decodeObject decodes a JSON object from data
+func decodeObject(data []byte) (map[string]any, error) {
+	var obj map[string]any
+	if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+		return nil, fmt.Errorf("%w: expected a JSON object", ErrInvalidData)
+	}
+	return obj, nil
+}
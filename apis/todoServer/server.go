@@ -4,14 +4,12 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"sync"
 )
 
-func newMux(todoFile string) http.Handler {
+func newMux(c *listCache) http.Handler {
 	m := http.NewServeMux()
-	mu := &sync.Mutex{}
 	m.HandleFunc("/", rootHandler)
-	t := todoRouter(todoFile, mu)
+	t := todoRouter(c)
 	m.Handle("/todo", http.StripPrefix("/todo", t))
 	m.Handle("/todo/", http.StripPrefix("/todo/", t))
 	return m
@@ -34,7 +32,13 @@ func replyJSONContent(w http.ResponseWriter, r *http.Request, status int, resp *
 	w.Write(body)
 }
 
+// replyError replies with the status. The message is logged, and
+// sent along for client errors so callers can tell what was wrong
 func replyError(w http.ResponseWriter, r *http.Request, status int, message string) {
 	log.Printf("%s %s: Error: %d %s", r.URL, r.Method, status, message)
-	http.Error(w, http.StatusText(status), status)
+	text := http.StatusText(status)
+	if status < http.StatusInternalServerError && message != "" {
+		text += ": " + message
+	}
+	http.Error(w, text, status)
 }
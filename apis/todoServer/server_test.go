@@ -8,7 +8,9 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"pragprog.com/rggo/interacting/todo"
 	"strings"
 	"testing"
@@ -20,7 +22,7 @@ func setupAPI(t *testing.T) (string, func()) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	ts := httptest.NewServer(newMux(tempTodoFile.Name()))
+	ts := httptest.NewServer(newMux(newListCache(tempTodoFile.Name(), 0)))
 
 	// Adding a couple of items for testing
 	for i := 1; i < 3; i++ {
@@ -46,6 +48,7 @@ func setupAPI(t *testing.T) (string, func()) {
 	return ts.URL, func() {
 		ts.Close()
 		os.Remove(tempTodoFile.Name())
+		os.Remove(tempTodoFile.Name() + ".lock")
 	}
 }
 
@@ -60,6 +63,7 @@ func TestGet(t *testing.T) {
 		path       string
 		expCode    int
 		expItems   int
+		expResults int
 		expContent string
 	}{
 		{
@@ -73,6 +77,7 @@ func TestGet(t *testing.T) {
 			path:       "/todo",
 			expCode:    http.StatusOK,
 			expItems:   2,
+			expResults: 2,
 			expContent: "Task number 1.",
 		},
 		{
@@ -80,8 +85,22 @@ func TestGet(t *testing.T) {
 			path:       "/todo/1",
 			expCode:    http.StatusOK,
 			expItems:   1,
+			expResults: 1,
 			expContent: "Task number 1.",
 		},
+		{
+			name:       "GetQuery",
+			path:       "/todo?q=" + url.QueryEscape(`open and "number 2"`),
+			expCode:    http.StatusOK,
+			expItems:   2,
+			expResults: 1,
+			expContent: "Task number 2.",
+		},
+		{
+			name:    "InvalidQuery",
+			path:    "/todo?q=" + url.QueryEscape("due<someday"),
+			expCode: http.StatusBadRequest,
+		},
 		{
 			name:    "NotFound",
 			path:    "/todo/500",
@@ -124,8 +143,11 @@ func TestGet(t *testing.T) {
 				if resp.TotalResults != tc.expItems {
 					t.Errorf("Expected %d items, got %d.", tc.expItems, resp.TotalResults)
 				}
-				if resp.Results[0].Task != tc.expContent {
-					t.Errorf("Expected %q, got %q", tc.expContent, resp.Results[0].Task)
+				if len(resp.Results.Items) != tc.expResults {
+					t.Fatalf("Expected %d results, got %d.", tc.expResults, len(resp.Results.Items))
+				}
+				if resp.Results.Items[0].Task != tc.expContent {
+					t.Errorf("Expected %q, got %q", tc.expContent, resp.Results.Items[0].Task)
 				}
 			default:
 				t.Fatalf("Unsupported Content-Type: %q", r.Header.Get("Content-Type"))
@@ -134,6 +156,105 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestGetFiltered(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "todotest")
+	err := todo.Update(name, func(l *todo.List) error {
+		for _, task := range []string{"Write docs", "Deploy app", "Review docs", "Deploy docs", "Fix bug"} {
+			l.Add(task)
+		}
+		if err := l.SetPriority(4, "A"); err != nil {
+			return err
+		}
+		if err := l.SetPriority(2, "B"); err != nil {
+			return err
+		}
+		if err := l.Complete(1); err != nil {
+			return err
+		}
+		return l.Complete(3)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(newMux(newListCache(name, 0)))
+	defer ts.Close()
+
+	testCases := []struct {
+		name     string
+		query    string
+		expCode  int
+		expIDs   []int
+		expLinks []string
+	}{
+		{name: "Done", query: "done=true", expCode: http.StatusOK, expIDs: []int{1, 3}},
+		{name: "Undone", query: "done=false", expCode: http.StatusOK, expIDs: []int{2, 4, 5}},
+		{name: "Search", query: "search=DOCS&done=0", expCode: http.StatusOK, expIDs: []int{4}},
+		{name: "Query", query: "q=deploy&sort=id&order=desc", expCode: http.StatusOK, expIDs: []int{4, 2}},
+		{name: "Created", query: "created_after=today&created_before=tomorrow", expCode: http.StatusOK, expIDs: []int{1, 2, 3, 4, 5}},
+		{name: "CreatedBefore", query: "created_before=today", expCode: http.StatusOK},
+		{name: "Completed", query: "completed_after=" + url.QueryEscape("1 hour ago"), expCode: http.StatusOK, expIDs: []int{1, 3}},
+		{name: "SortTask", query: "sort=task", expCode: http.StatusOK, expIDs: []int{2, 4, 5, 3, 1}},
+		{name: "SortPriority", query: "sort=priority&order=desc", expCode: http.StatusOK, expIDs: []int{2, 4, 1, 3, 5}},
+		{
+			name: "FirstPage", query: "sort=id&limit=2", expCode: http.StatusOK, expIDs: []int{1, 2},
+			expLinks: []string{`</todo?limit=2&offset=0&sort=id>; rel="first"`, `</todo?limit=2&offset=2&sort=id>; rel="next"`, `</todo?limit=2&offset=4&sort=id>; rel="last"`},
+		},
+		{
+			name: "MiddlePage", query: "limit=2&offset=2", expCode: http.StatusOK, expIDs: []int{3, 4},
+			expLinks: []string{`</todo?limit=2&offset=0>; rel="first"`, `</todo?limit=2&offset=0>; rel="prev"`, `</todo?limit=2&offset=4>; rel="next"`, `</todo?limit=2&offset=4>; rel="last"`},
+		},
+		{
+			name: "LastPage", query: "done=false&limit=2&offset=2", expCode: http.StatusOK, expIDs: []int{5},
+			expLinks: []string{`</todo?done=false&limit=2&offset=0>; rel="first"`, `</todo?done=false&limit=2&offset=0>; rel="prev"`, `</todo?done=false&limit=2&offset=2>; rel="last"`},
+		},
+		{
+			name: "PastLastPage", query: "limit=2&offset=10", expCode: http.StatusOK,
+			expLinks: []string{`</todo?limit=2&offset=0>; rel="first"`, `</todo?limit=2&offset=4>; rel="prev"`, `</todo?limit=2&offset=4>; rel="last"`},
+		},
+		{
+			name: "HugeLimit", query: "sort=id&limit=9223372036854775807&offset=1", expCode: http.StatusOK, expIDs: []int{2, 3, 4, 5},
+			expLinks: []string{`</todo?limit=9223372036854775807&offset=0&sort=id>; rel="first"`, `</todo?limit=9223372036854775807&offset=0&sort=id>; rel="prev"`, `</todo?limit=9223372036854775807&offset=0&sort=id>; rel="last"`},
+		},
+		{name: "InvalidDone", query: "done=maybe", expCode: http.StatusBadRequest},
+		{name: "InvalidDate", query: "created_after=someday", expCode: http.StatusBadRequest},
+		{name: "InvalidSort", query: "sort=size", expCode: http.StatusBadRequest},
+		{name: "InvalidOrder", query: "sort=id&order=up", expCode: http.StatusBadRequest},
+		{name: "InvalidLimit", query: "limit=-1", expCode: http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := http.Get(ts.URL + "/todo?" + tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Body.Close()
+			if r.StatusCode != tc.expCode {
+				t.Fatalf("Expected %q, got %q.", http.StatusText(tc.expCode), http.StatusText(r.StatusCode))
+			}
+			if tc.expCode != http.StatusOK {
+				return
+			}
+			var resp todoResponse
+			if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.TotalResults != 5 {
+				t.Errorf("Expected total results to be the 5 items, got %d.", resp.TotalResults)
+			}
+			var ids []int
+			for _, item := range resp.Results.Items {
+				ids = append(ids, item.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tc.expIDs) {
+				t.Errorf("Expected items %v, got %v.", tc.expIDs, ids)
+			}
+			if links := strings.Join(tc.expLinks, ", "); r.Header.Get("Link") != links {
+				t.Errorf("Expected links %q, got %q.", links, r.Header.Get("Link"))
+			}
+		})
+	}
+}
+
 func TestAdd(t *testing.T) {
 	url, cleanup := setupAPI(t)
 	defer cleanup()
@@ -158,10 +279,10 @@ func TestAdd(t *testing.T) {
 	})
 	t.Run("CheckAdd", func(t *testing.T) {
 		r, err := http.Get(url + "/todo/3")
-		defer r.Body.Close()
 		if err != nil {
 			t.Fatal(err)
 		}
+		defer r.Body.Close()
 		if r.StatusCode != http.StatusOK {
 			t.Errorf("Expected %q, got %q.", http.StatusText(http.StatusOK), http.StatusText(r.StatusCode))
 		}
@@ -169,8 +290,8 @@ func TestAdd(t *testing.T) {
 		if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
 			t.Fatal(err)
 		}
-		if resp.Results[0].Task != taskName {
-			t.Errorf("Expected %q, got %q.", taskName, resp.Results[0].Task)
+		if resp.Results.Items[0].Task != taskName {
+			t.Errorf("Expected %q, got %q.", taskName, resp.Results.Items[0].Task)
 		}
 	})
 }
@@ -195,10 +316,10 @@ func TestDelete(t *testing.T) {
 	})
 	t.Run("CheckDelete", func(t *testing.T) {
 		r, err := http.Get(url + "/todo")
-		defer r.Body.Close()
 		if err != nil {
 			t.Fatal(err)
 		}
+		defer r.Body.Close()
 		if r.StatusCode != http.StatusOK {
 			t.Errorf("Expected %q, got %q.", http.StatusText(http.StatusOK), http.StatusText(r.StatusCode))
 		}
@@ -206,12 +327,12 @@ func TestDelete(t *testing.T) {
 		if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
 			t.Fatal(err)
 		}
-		if len(resp.Results) != 1 {
-			t.Errorf("Expected 1 item, got %d.", len(resp.Results))
+		if len(resp.Results.Items) != 1 {
+			t.Errorf("Expected 1 item, got %d.", len(resp.Results.Items))
 		}
 		expTask := "Task number 2."
-		if resp.Results[0].Task != expTask {
-			t.Errorf("Expected %q, got %q.", expTask, resp.Results[0].Task)
+		if resp.Results.Items[0].Task != expTask {
+			t.Errorf("Expected %q, got %q.", expTask, resp.Results.Items[0].Task)
 		}
 	})
 }
@@ -235,10 +356,10 @@ func TestComplete(t *testing.T) {
 	})
 	t.Run("CheckComplete", func(t *testing.T) {
 		r, err := http.Get(url + "/todo")
-		defer r.Body.Close()
 		if err != nil {
 			t.Fatal(err)
 		}
+		defer r.Body.Close()
 		if r.StatusCode != http.StatusOK {
 			t.Errorf("Expected %q, got %q.", http.StatusText(http.StatusOK), http.StatusText(r.StatusCode))
 		}
@@ -246,14 +367,362 @@ func TestComplete(t *testing.T) {
 		if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
 			t.Fatal(err)
 		}
-		if len(resp.Results) != 2 {
-			t.Errorf("Expected 2 items, got %d.", len(resp.Results))
+		if len(resp.Results.Items) != 2 {
+			t.Errorf("Expected 2 items, got %d.", len(resp.Results.Items))
 		}
-		if !resp.Results[0].Done {
+		if !resp.Results.Items[0].Done() {
 			t.Errorf("Expected Item 1 to be completed")
 		}
-		if resp.Results[1].Done {
+		if resp.Results.Items[1].Done() {
 			t.Errorf("Expected Item 2 not to be completed")
 		}
 	})
 }
+
+func TestUpdate(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+	testCases := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		expCode     int
+		expContent  string
+		check       func(t *testing.T, item todo.List)
+	}{
+		{
+			name: "PatchTask", method: http.MethodPatch, path: "/todo/1",
+			contentType: "application/merge-patch+json", body: `{"task": "Renamed", "Tags": ["work"]}`,
+			expCode: http.StatusOK,
+			check: func(t *testing.T, l todo.List) {
+				if l.Items[0].Task != "Renamed" || fmt.Sprint(l.Items[0].Tags) != "[work]" {
+					t.Errorf("Expected the task and tags to change, got %q %v.", l.Items[0].Task, l.Items[0].Tags)
+				}
+			},
+		},
+		{
+			name: "PatchDone", method: http.MethodPatch, path: "/todo/1",
+			contentType: "application/merge-patch+json", body: `{"Done": true}`,
+			expCode: http.StatusOK,
+			check: func(t *testing.T, l todo.List) {
+				if !l.Items[0].Done() || l.Items[0].Task != "Renamed" || len(l.Items[0].Tags) != 1 {
+					t.Errorf("Expected only the item to be completed, got %+v.", l.Items[0])
+				}
+			},
+		},
+		{
+			name: "PatchReopen", method: http.MethodPatch, path: "/todo/1",
+			contentType: "application/json", body: `{"done": false, "tags": null}`,
+			expCode: http.StatusOK,
+			check: func(t *testing.T, l todo.List) {
+				if l.Items[0].Done() || !l.Items[0].CompletedAt.IsZero() || len(l.Items[0].Tags) != 0 {
+					t.Errorf("Expected the item to be reopened without tags, got %+v.", l.Items[0])
+				}
+			},
+		},
+		{
+			name: "Put", method: http.MethodPut, path: "/todo/2",
+			contentType: "application/json", body: `{"Task": "Replaced", "Priority": "a"}`,
+			expCode: http.StatusOK,
+			check: func(t *testing.T, l todo.List) {
+				if l.Items[0].ID != 2 || l.Items[0].Task != "Replaced" || l.Items[0].Priority != "A" {
+					t.Errorf("Expected the item to be replaced, got %+v.", l.Items[0])
+				}
+			},
+		},
+		{
+			name: "PutMissingTask", method: http.MethodPut, path: "/todo/2",
+			contentType: "application/json", body: `{"Done": true}`,
+			expCode: http.StatusUnprocessableEntity, expContent: todo.ErrEmptyTask.Error(),
+		},
+		{
+			name: "PatchInvalidPriority", method: http.MethodPatch, path: "/todo/2",
+			contentType: "application/merge-patch+json", body: `{"Priority": "high"}`,
+			expCode: http.StatusUnprocessableEntity, expContent: "priority must be a single letter",
+		},
+		{
+			name: "PatchInvalidStatus", method: http.MethodPatch, path: "/todo/2",
+			contentType: "application/merge-patch+json", body: `{"Status":"banana"}`,
+			expCode: http.StatusUnprocessableEntity, expContent: todo.ErrInvalidStatus.Error(),
+		},
+		{
+			name: "PatchInvalidRecurrence", method: http.MethodPatch, path: "/todo/2",
+			contentType: "application/merge-patch+json", body: `{"Recur":{"Kind":"fortnightly"}}`,
+			expCode: http.StatusUnprocessableEntity, expContent: todo.ErrInvalidRecurrence.Error(),
+		},
+		{
+			name: "PutInvalidStatus", method: http.MethodPut, path: "/todo/2",
+			contentType: "application/json", body: `{"Task": "Replaced", "Status":"banana"}`,
+			expCode: http.StatusUnprocessableEntity, expContent: todo.ErrInvalidStatus.Error(),
+		},
+		{
+			name: "PatchID", method: http.MethodPatch, path: "/todo/2",
+			contentType: "application/merge-patch+json", body: `{"ID": 1}`,
+			expCode: http.StatusUnprocessableEntity, expContent: "cannot change",
+		},
+		{
+			name: "PatchNotObject", method: http.MethodPatch, path: "/todo/2",
+			contentType: "application/merge-patch+json", body: `["Task"]`,
+			expCode: http.StatusBadRequest, expContent: "expected a JSON object",
+		},
+		{
+			name: "PatchContentType", method: http.MethodPatch, path: "/todo/2",
+			contentType: "text/plain", body: `{"Task": "Text"}`,
+			expCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "PutNotFound", method: http.MethodPut, path: "/todo/5",
+			contentType: "application/json", body: `{"Task": "Missing"}`,
+			expCode: http.StatusNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, url+tc.path, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", tc.contentType)
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Body.Close()
+			if r.StatusCode != tc.expCode {
+				t.Fatalf("Expected %q, got %q.", http.StatusText(tc.expCode), http.StatusText(r.StatusCode))
+			}
+			if tc.check == nil {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(body), tc.expContent) {
+					t.Errorf("Expected %q in the error, got %q.", tc.expContent, string(body))
+				}
+				return
+			}
+			var resp todoResponse
+			if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Results.Items) != 1 {
+				t.Fatalf("Expected the updated item, got %d items.", len(resp.Results.Items))
+			}
+			tc.check(t, resp.Results)
+		})
+	}
+}
+
+func TestCompleteBlocked(t *testing.T) {
+	// The API cannot declare dependencies, so the list is set up directly
+	name := filepath.Join(t.TempDir(), "todotest")
+	err := todo.Update(name, func(l *todo.List) error {
+		l.Add("Task number 1.")
+		l.Add("Task number 2.")
+		return l.AddDependency(2, 1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(newMux(newListCache(name, 0)))
+	defer ts.Close()
+
+	complete := func(t *testing.T, id int, expCode int) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/todo/%d?complete", ts.URL, id), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+		if r.StatusCode != expCode {
+			t.Fatalf("Expected %q, got %q.", http.StatusText(expCode), http.StatusText(r.StatusCode))
+		}
+	}
+	t.Run("Blocked", func(t *testing.T) {
+		complete(t, 2, http.StatusConflict)
+	})
+	t.Run("Unblocked", func(t *testing.T) {
+		complete(t, 1, http.StatusNoContent)
+		complete(t, 2, http.StatusNoContent)
+	})
+}
+
+func TestUpdateWorkflow(t *testing.T) {
+	// The workflow is set with the todo command in the catalog of lists
+	name := filepath.Join(t.TempDir(), "todotest")
+	err := todo.Update(name, func(l *todo.List) error {
+		l.Add("Task number 1.")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := todo.ParseWorkflow("todo,review,done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := todo.UpdateCatalog(name+".lists", func(c *todo.Catalog) error {
+		c.Workflow = w
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(newMux(newListCache(name, 0)))
+	defer ts.Close()
+
+	testCases := []struct {
+		name    string
+		method  string
+		body    string
+		expCode int
+	}{
+		{name: "PatchConfigured", method: http.MethodPatch, body: `{"Status":"review"}`, expCode: http.StatusOK},
+		{name: "PatchDefault", method: http.MethodPatch, body: `{"Status":"blocked"}`, expCode: http.StatusUnprocessableEntity},
+		{name: "PutDefault", method: http.MethodPut, body: `{"Task":"Task number 1.","Status":"in-progress"}`, expCode: http.StatusUnprocessableEntity},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, ts.URL+"/todo/1", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+			if r.StatusCode != tc.expCode {
+				t.Fatalf("Expected %q, got %q.", http.StatusText(tc.expCode), http.StatusText(r.StatusCode))
+			}
+		})
+	}
+}
+
+func TestStableID(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+	t.Run("DeleteFirst", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, url+"/todo/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.StatusCode != http.StatusNoContent {
+			t.Fatalf("Expected %q, got %q.", http.StatusText(http.StatusNoContent), http.StatusText(r.StatusCode))
+		}
+	})
+	t.Run("DeletedNotFound", func(t *testing.T) {
+		r, err := http.Get(url + "/todo/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+		if r.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected %q, got %q.", http.StatusText(http.StatusNotFound), http.StatusText(r.StatusCode))
+		}
+	})
+	t.Run("GetByID", func(t *testing.T) {
+		r, err := http.Get(url + "/todo/2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+		if r.StatusCode != http.StatusOK {
+			t.Fatalf("Expected %q, got %q.", http.StatusText(http.StatusOK), http.StatusText(r.StatusCode))
+		}
+		var resp todoResponse
+		if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		expTask := "Task number 2."
+		if resp.Results.Items[0].ID != 2 || resp.Results.Items[0].Task != expTask {
+			t.Errorf("Expected item 2 %q, got item %d %q.", expTask, resp.Results.Items[0].ID, resp.Results.Items[0].Task)
+		}
+	})
+}
+
+func TestETags(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+	// send sends a request with the header, checking the status of the reply
+	send := func(t *testing.T, method, path, header, value string, expCode int) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, url+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { r.Body.Close() })
+		if r.StatusCode != expCode {
+			t.Fatalf("Expected %q, got %q.", http.StatusText(expCode), http.StatusText(r.StatusCode))
+		}
+		return r
+	}
+
+	var listTag string
+	var resp todoResponse
+	t.Run("GetAll", func(t *testing.T) {
+		r := send(t, http.MethodGet, "/todo", "", "", http.StatusOK)
+		if listTag = r.Header.Get("ETag"); listTag == "" {
+			t.Fatal("Expected an ETag for the list.")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.ETags) != 2 || resp.ETags[1] == resp.ETags[2] {
+			t.Fatalf("Expected distinct tags for both items, got %v.", resp.ETags)
+		}
+	})
+	t.Run("GetOne", func(t *testing.T) {
+		r := send(t, http.MethodGet, "/todo/1", "", "", http.StatusOK)
+		if tag := r.Header.Get("ETag"); tag != resp.ETags[1] {
+			t.Errorf("Expected the tag %s of the list, got %s.", resp.ETags[1], tag)
+		}
+	})
+	t.Run("NotModified", func(t *testing.T) {
+		send(t, http.MethodGet, "/todo", "If-None-Match", listTag, http.StatusNotModified)
+		send(t, http.MethodGet, "/todo", "If-None-Match", `"other", W/`+listTag, http.StatusNotModified)
+		send(t, http.MethodGet, "/todo/2", "If-None-Match", resp.ETags[2], http.StatusNotModified)
+		send(t, http.MethodGet, "/todo/1", "If-None-Match", resp.ETags[2], http.StatusOK)
+	})
+	t.Run("CompleteMatch", func(t *testing.T) {
+		r := send(t, http.MethodPatch, "/todo/1?complete", "If-Match", resp.ETags[1], http.StatusNoContent)
+		if tag := r.Header.Get("ETag"); tag == "" || tag == resp.ETags[1] {
+			t.Errorf("Expected a new tag for the completed item, got %q.", tag)
+		}
+	})
+	t.Run("Changed", func(t *testing.T) {
+		send(t, http.MethodGet, "/todo", "If-None-Match", listTag, http.StatusOK)
+		r := send(t, http.MethodPatch, "/todo/1?complete", "If-Match", resp.ETags[1], http.StatusPreconditionFailed)
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), "item 1 changed") {
+			t.Errorf("Expected the changed item in the error, got %q.", string(body))
+		}
+		send(t, http.MethodDelete, "/todo/2", "If-Match", listTag, http.StatusPreconditionFailed)
+		send(t, http.MethodDelete, "/todo/2", "If-Match", "W/"+resp.ETags[2], http.StatusPreconditionFailed)
+	})
+	t.Run("DeleteMatch", func(t *testing.T) {
+		// Completing item 1 left the tag of item 2 unchanged
+		send(t, http.MethodDelete, "/todo/2", "If-Match", resp.ETags[2], http.StatusNoContent)
+		send(t, http.MethodGet, "/todo/2", "", "", http.StatusNotFound)
+	})
+}
@@ -6,19 +6,36 @@ import (
 	"time"
 )
 
+// todoResponse holds the items returned along with the number of
+// items they were taken from, before filtering and pagination, and
+// the entity tags of the items by ID, to send back in If-Match
 type todoResponse struct {
-	Results todo.List `json:"results"`
+	Results      todo.List      `json:"results"`
+	TotalResults int            `json:"total_results"`
+	ETags        map[int]string `json:"etags,omitempty"`
 }
 
+// MarshalJSON implements json.Marshaler. The results are always
+// an array of items, without the last ID of the list
 func (r *todoResponse) MarshalJSON() ([]byte, error) {
+	results := json.RawMessage("[]")
+	if len(r.Results.Items) > 0 {
+		data, err := json.Marshal(r.Results.Items)
+		if err != nil {
+			return nil, err
+		}
+		results = data
+	}
 	resp := struct {
-		Results      todo.List `json:"results"`
-		Date         int64     `json:"date"`
-		TotalResults int       `json:"total_results"`
+		Results      json.RawMessage `json:"results"`
+		Date         int64           `json:"date"`
+		TotalResults int             `json:"total_results"`
+		ETags        map[int]string  `json:"etags,omitempty"`
 	}{
-		Results:      r.Results,
+		Results:      results,
 		Date:         time.Now().Unix(),
-		TotalResults: len(r.Results),
+		TotalResults: r.TotalResults,
+		ETags:        r.ETags,
 	}
 	return json.Marshal(resp)
 }
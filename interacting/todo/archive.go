@@ -0,0 +1,129 @@
+package todo
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Archive moves the items of the list named list completed before
+// the time before to archive and returns how many were moved.
+// Only whole trees are archived: subtasks go along with their top
+// level item, which stays in the list until all of them can be archived.
+// Archived items keep their ID unless it is already in the archive
+func (l *List) Archive(archive *List, list string, before time.Time) int {
+	archivable := func(value item) bool {
+		return value.ListName() == list && value.Done() && value.CompletedAt.Before(before)
+	}
+	var ids []int
+	for _, value := range l.Items {
+		if _, err := l.Index(value.Parent); err == nil || !archivable(value) {
+			continue
+		}
+		all := true
+		for _, d := range l.descendants(value.ID) {
+			i, _ := l.Index(d)
+			if !archivable(l.Items[i]) {
+				all = false
+				break
+			}
+		}
+		if all {
+			ids = append(ids, value.ID)
+			ids = append(ids, l.descendants(value.ID)...)
+		}
+	}
+	l.transfer(archive, ids)
+	return len(ids)
+}
+
+// Restore moves the archived item with the given ID, along with its
+// archived subtasks, back to the list and returns its ID in the list.
+// Items archived from the list keep their ID, which the list never
+// hands out again, but items archived from another list get a new
+// ID when theirs is taken
+func (l *List) Restore(archive *List, id int) (int, error) {
+	if _, err := archive.Index(id); err != nil {
+		return 0, err
+	}
+	ids := append([]int{id}, archive.descendants(id)...)
+	renumbered := archive.transfer(l, ids)
+	if n, ok := renumbered[id]; ok {
+		return n, nil
+	}
+	return id, nil
+}
+
+// transfer moves the items with the given IDs to dst. Items whose ID
+// is already in dst get a new one, and their subtasks follow them.
+// Dependencies between moved and remaining items are dropped.
+// It returns the new IDs of the renumbered items
+func (l *List) transfer(dst *List, ids []int) map[int]int {
+	var moved []item
+	for _, value := range l.Items {
+		if slices.Contains(ids, value.ID) {
+			moved = append(moved, value)
+		}
+	}
+	l.Items = slices.DeleteFunc(l.Items, func(value item) bool {
+		return slices.Contains(ids, value.ID)
+	})
+	l.dropDependencies(ids)
+	for i := range moved {
+		moved[i].DependsOn = slices.DeleteFunc(moved[i].DependsOn, func(d int) bool {
+			return !slices.Contains(ids, d)
+		})
+	}
+
+	renumbered := map[int]int{}
+	for i := range moved {
+		if _, err := dst.Index(moved[i].ID); err == nil {
+			renumbered[moved[i].ID] = 0
+		}
+	}
+	// The IDs kept are reserved in dst before handing out new ones
+	for _, value := range moved {
+		if _, ok := renumbered[value.ID]; !ok {
+			dst.LastID = max(dst.LastID, value.ID)
+		}
+	}
+	for i := range moved {
+		if _, ok := renumbered[moved[i].ID]; ok {
+			n := dst.newID()
+			renumbered[moved[i].ID] = n
+			moved[i].ID = n
+		}
+	}
+	for i := range moved {
+		if n, ok := renumbered[moved[i].Parent]; ok {
+			moved[i].Parent = n
+		}
+		if n, ok := renumbered[moved[i].Spawned]; ok {
+			moved[i].Spawned = n
+		}
+		moved[i].DependsOn = renumberIDs(moved[i].DependsOn, renumbered)
+	}
+	dst.Items = append(dst.Items, moved...)
+	return renumbered
+}
+
+// SetArchiveAfter archives the completed items of the list with the
+// given name automatically, once they have been completed for days.
+// Zero days turns the automatic archive off
+func (c *Catalog) SetArchiveAfter(name string, days int) error {
+	if !c.Has(name) {
+		return fmt.Errorf("%w: %s", ErrListNotExists, name)
+	}
+	if days < 0 {
+		return fmt.Errorf("number of days must not be negative, got %d", days)
+	}
+	if days == 0 {
+		delete(c.ArchiveAfter, name)
+		return nil
+	}
+	if c.ArchiveAfter == nil {
+		c.ArchiveAfter = map[string]int{}
+	}
+	c.ArchiveAfter[name] = days
+	return nil
+}
@@ -0,0 +1,68 @@
+package todo_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+	"time"
+)
+
+func TestList_Archive(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	release := l.Add("Release")
+	tag, err := l.AddSub(release, "Tag")
+	require.NoError(t, err)
+	notes, err := l.AddSub(release, "Notes")
+	require.NoError(t, err)
+	other := l.Add("Other list")
+	require.NoError(t, l.Move(other, "work"))
+	for _, id := range []int{1, release, tag, other} {
+		require.NoError(t, l.Complete(id))
+	}
+
+	archive := todo.List{}
+	cutoff := time.Now().Add(time.Second)
+	assert.Equal(t, 0, l.Archive(&archive, todo.DefaultList, time.Now().AddDate(0, 0, -1)), "expected recent items to stay")
+	assert.Equal(t, 1, l.Archive(&archive, todo.DefaultList, cutoff), "expected open subtasks to keep their tree")
+	assert.Equal(t, "  2: Task 2\nX 3: Release [1/2]\nX   4: Tag\n    5: Notes\nX 6: Other list\n", l.String())
+
+	require.NoError(t, l.Complete(notes))
+	assert.Equal(t, 3, l.Archive(&archive, todo.DefaultList, cutoff))
+	assert.Equal(t, "X 1: Task 1\nX 3: Release [2/2]\nX   4: Tag\nX   5: Notes\n", archive.String())
+
+	t.Run("Restore", func(t *testing.T) {
+		id, err := l.Restore(&archive, release)
+		require.NoError(t, err)
+		assert.Equal(t, release, id)
+		assert.Equal(t, "X 1: Task 1\n", archive.String())
+		_, err = l.Restore(&archive, release)
+		assert.ErrorIs(t, err, todo.ErrNotExists)
+	})
+
+	t.Run("RestoreKeepsID", func(t *testing.T) {
+		require.NoError(t, l.Delete(2))
+		require.NoError(t, l.Delete(release))
+		require.NoError(t, l.Delete(other))
+		assert.Equal(t, 7, l.Add("New ID"), "expected archived IDs not to be reused")
+		id, err := l.Restore(&archive, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, id)
+		assert.Equal(t, "  7: New ID\nX 1: Task 1\n", l.String())
+		assert.Empty(t, archive.Items)
+	})
+}
+
+func TestCatalog_SetArchiveAfter(t *testing.T) {
+	c := &todo.Catalog{}
+	require.NoError(t, c.Create("work"))
+	require.NoError(t, c.SetArchiveAfter("work", 7))
+	assert.ErrorIs(t, c.SetArchiveAfter("home", 7), todo.ErrListNotExists)
+	assert.Error(t, c.SetArchiveAfter("work", -1))
+	require.NoError(t, c.Rename("work", "job"))
+	assert.Equal(t, map[string]int{"job": 7}, c.ArchiveAfter)
+	require.NoError(t, c.SetArchiveAfter("job", 0))
+	assert.Empty(t, c.ArchiveAfter)
+}
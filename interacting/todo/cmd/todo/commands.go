@@ -0,0 +1,688 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"pragprog.com/rggo/interacting/todo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// command is a subcommand of the tool
+type command struct {
+	name    string
+	args    string
+	summary string
+	// min and max bound the number of arguments, max < 0 for no bound
+	min, max int
+	// noStore is set for the commands that do not work on the list
+	noStore bool
+	// noArchive is set for the commands that leave the list unchanged,
+	// and for undo and redo, which find it as the journal left it, so
+	// the lists with auto-archive are not archived before they run
+	noArchive bool
+	// values are completed as the arguments of the command, and
+	// files tells whether file names are completed as well
+	values []string
+	files  bool
+	// setup defines the flags of the command on fs and returns
+	// the function running it with the arguments left after the flags
+	setup func(fs *flag.FlagSet) func(a *app, args []string) error
+}
+
+// commands returns the subcommands of the tool in the order shown by help
+func commands() []command {
+	return []command{
+		{name: "add", args: "[TASK...]", summary: "Add a task, or one task per line from STDIN", max: -1, setup: addCommand},
+		{name: "list", summary: "List the tasks of the active list", noArchive: true, setup: listCommand},
+		{name: "complete", args: "IDS", summary: "Complete tasks, i.e. 1-5,8", min: 1, max: 1, setup: completeCommand},
+		{name: "reopen", args: "IDS", summary: "Reopen completed tasks", min: 1, max: 1, setup: rangeCommand((*todo.List).Reopen)},
+		{name: "delete", args: "IDS", summary: "Delete tasks along with their subtasks", min: 1, max: 1, setup: rangeCommand((*todo.List).Delete)},
+		{name: "edit", args: "ID [TASK...]", summary: "Replace the task, or edit it with $VISUAL or $EDITOR", min: 1, max: -1, setup: editCommand},
+		{name: "depend", args: "ID IDS", summary: "Make a task wait for other tasks", min: 2, max: 2, setup: dependCommand(false)},
+		{name: "undepend", args: "ID IDS", summary: "Stop a task from waiting for other tasks", min: 2, max: 2, setup: dependCommand(true)},
+		{name: "status", args: "ID STATUS", summary: "Move a task to a status of the workflow", min: 2, max: 2, setup: statusCommand},
+		{name: "board", summary: "Show the tasks of the active list with a column per status", noArchive: true, setup: boardCommand},
+		{name: "tui", summary: "Browse and edit the tasks of the active list in a full screen interface", setup: tuiCommand},
+		{name: "workflow", args: "STATUSES", summary: "Set the statuses, starting with todo and ending with done", min: 1, max: 1, noArchive: true, setup: workflowCommand},
+		{name: "lists", summary: "Show the lists and how many tasks they have", noArchive: true, setup: listsCommand},
+		{name: "new-list", args: "NAME", summary: "Create a new empty list", min: 1, max: 1, noArchive: true, setup: newListCommand},
+		{name: "use", args: "NAME", summary: "Make the list the active one", min: 1, max: 1, noArchive: true, setup: useCommand},
+		{name: "rename-list", args: "OLD NEW", summary: "Rename a list", min: 2, max: 2, setup: renameListCommand},
+		{name: "delete-list", args: "NAME", summary: "Delete a list along with its tasks", min: 1, max: 1, setup: deleteListCommand},
+		{name: "move", args: "ID LIST", summary: "Move a task with its subtasks to another list", min: 2, max: 2, setup: moveCommand},
+		{name: "new-template", args: "NAME [ITEM...]", summary: "Create a template with an item per argument, or per line from STDIN", min: 1, max: -1, noArchive: true, setup: newTemplateCommand},
+		{name: "templates", args: "[NAME]", summary: "Show the templates with their items and parameters", max: 1, noArchive: true, setup: templatesCommand},
+		{name: "instantiate", args: "NAME [PARAM=VALUE...]", summary: "Add the items of a template, replacing parameters such as {version}", min: 1, max: -1, setup: instantiateCommand},
+		{name: "delete-template", args: "NAME", summary: "Delete a template", min: 1, max: 1, noArchive: true, setup: deleteTemplateCommand},
+		{name: "archive", summary: "Archive the completed tasks of the active list", setup: archiveCommand},
+		{name: "auto-archive", args: "DAYS", summary: "Archive the tasks of the active list once completed for DAYS, or never with 0", min: 1, max: 1, setup: autoArchiveCommand},
+		{name: "restore", args: "ID", summary: "Restore an archived task", min: 1, max: 1, setup: restoreCommand},
+		{name: "report", args: "[day|week]", summary: "Report the completed tasks per day or week", max: 1, values: []string{todo.PeriodDay, todo.PeriodWeek}, noArchive: true, setup: reportCommand},
+		{name: "import", args: "FILE", summary: "Import tasks from a todo.txt file", min: 1, max: 1, files: true, setup: importCommand},
+		{name: "export", args: "[FILE]", summary: "Export the tasks to a todo.txt file, STDOUT by default", max: 1, files: true, noArchive: true, setup: exportCommand},
+		{name: "undo", summary: "Undo the last change", noArchive: true, setup: undoCommand(false)},
+		{name: "redo", summary: "Redo the last undone change", noArchive: true, setup: undoCommand(true)},
+		{name: "history", summary: "Show the history of changes", noArchive: true, setup: historyCommand},
+		{name: "passwd", summary: "Encrypt the tasks with a new passphrase, change it or remove it", noStore: true, setup: passwdCommand},
+		{name: "merge", args: "BASE OURS THEIRS", summary: "Merge two versions of the tasks into OURS, as a git merge driver", min: 3, max: 3, noStore: true, files: true, setup: mergeCommand},
+		{name: "completion", args: "SHELL", summary: "Print the completion script of bash, zsh or fish", min: 1, max: 1, noStore: true, values: shells, setup: completionCommand},
+		{name: "help", args: "[COMMAND]", summary: "Show the usage of the tool or of a command", max: 1, noStore: true, setup: helpCommand},
+	}
+}
+
+// findCommand returns the command with the given name
+func findCommand(name string) (command, bool) {
+	for _, c := range commands() {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// newFlagSet returns the flag set of the command along with
+// the function running it
+func (c command) newFlagSet() (*flag.FlagSet, func(a *app, args []string) error) {
+	fs := flag.NewFlagSet("todo "+c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: todo %s [flags] %s\n%s\n", c.name, c.args, c.summary)
+		fs.PrintDefaults()
+	}
+	return fs, c.setup(fs)
+}
+
+// parseID parses the ID of an item given as an argument
+func parseID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("%w: invalid ID %q", errUsage, s)
+	}
+	return id, nil
+}
+
+func addCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	due := fs.String("due", "", "Due date, i.e. 2024-01-31, tomorrow, \"next friday 3pm\" or \"in 2 weeks\"")
+	priority := fs.String("priority", "", "Priority (A-Z)")
+	tags := fs.String("tags", "", "Comma separated tags")
+	parent := fs.Int("parent", 0, "ID of the item the tasks are subtasks of")
+	recur := fs.String("recur", "", "Repeat the tasks: daily, weekly[:mon,...], monthly[:day] or after:<days>")
+	deps := fs.String("deps", "", "Comma separated IDs of the items the tasks depend on")
+	return func(a *app, args []string) error {
+		// When any arguments are provided, they will be used as the new task
+		t, err := getTask(os.Stdin, args...)
+		if err != nil {
+			return err
+		}
+		return a.s.Update(func(l *todo.List) error {
+			for _, task := range strings.Split(t, "\n") {
+				if task == "" {
+					continue
+				}
+				id := 0
+				if *parent > 0 {
+					var err error
+					if id, err = l.AddSub(*parent, task); err != nil {
+						return err
+					}
+				} else {
+					id = l.Add(task)
+					if err := l.Move(id, a.active); err != nil {
+						return err
+					}
+				}
+				if err := plan(l, id, *due, *priority, *tags, *recur); err != nil {
+					return err
+				}
+				if err := dependOn(l, id, *deps, false); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+func listCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	all := fs.Bool("all", false, "List the tasks of all lists")
+	open := fs.Bool("open", false, "Leave out the completed tasks")
+	ready := fs.Bool("ready", false, "List the open tasks that do not wait for other tasks")
+	query := fs.String("query", "", "List the tasks matching a filter expression, i.e. 'open and created>=-7d and deploy'")
+	createdSince := fs.String("created-since", "", "List the tasks created since the date, i.e. yesterday or \"2 weeks ago\"")
+	archived := fs.Bool("archived", false, "List the archived tasks")
+	verbose := fs.Bool("verbose", false, "Show the time of creation and completion")
+	return func(a *app, args []string) error {
+		var q *todo.Query
+		if *query != "" {
+			var err error
+			if q, err = todo.ParseQuery(*query, time.Now()); err != nil {
+				return err
+			}
+		}
+		var since time.Time
+		if *createdSince != "" {
+			var err error
+			if since, _, err = todo.ParseDate(*createdSince, time.Now()); err != nil {
+				return err
+			}
+		}
+		source := a.l
+		if *archived {
+			source = &todo.List{}
+			if err := a.a.Load(source); err != nil {
+				return err
+			}
+		}
+		names := []string{a.active}
+		if *all {
+			names = append([]string{todo.DefaultList}, a.c.Names...)
+		}
+		for _, name := range names {
+			items := source.In(name)
+			if q != nil {
+				items = items.Select(q)
+			}
+			if *ready {
+				items = items.Ready()
+			}
+			if *open || !since.IsZero() {
+				// Copying items keeps the whole list their blockers are in
+				filtered := items
+				filtered.Items = nil
+				for _, value := range items.Items {
+					if (*open && value.Done()) || value.CreatedAt.Before(since) {
+						continue
+					}
+					filtered.Items = append(filtered.Items, value)
+				}
+				items = filtered
+			}
+			items.Sort()
+			if *all {
+				fmt.Printf("%s:\n", name)
+			}
+			if *verbose {
+				fmt.Print(describe(items))
+				continue
+			}
+			fmt.Print(&items)
+		}
+		return nil
+	}
+}
+
+func completeCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	children := fs.Bool("children", false, "Complete the subtasks of the items as well")
+	force := fs.Bool("force", false, "Complete the items even if the tasks they depend on are still open")
+	return func(a *app, args []string) error {
+		ids, err := todo.ParseRange(args[0])
+		if err != nil {
+			return err
+		}
+		// Complete the given items and save the new list,
+		// leaving it untouched if any of them fails
+		return a.s.Update(func(l *todo.List) error {
+			switch {
+			case *children:
+				return l.Apply(ids, (*todo.List).CompleteTree)
+			case *force:
+				return l.Apply(ids, (*todo.List).ForceComplete)
+			}
+			return l.CompleteAll(ids)
+		})
+	}
+}
+
+// rangeCommand returns the setup of a command applying fn
+// to the items in the range given as argument
+func rangeCommand(fn func(l *todo.List, id int) error) func(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(fs *flag.FlagSet) func(a *app, args []string) error {
+		return func(a *app, args []string) error {
+			ids, err := todo.ParseRange(args[0])
+			if err != nil {
+				return err
+			}
+			return a.s.Update(func(l *todo.List) error {
+				return l.Apply(ids, fn)
+			})
+		}
+	}
+}
+
+func editCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		i, err := a.l.Index(id)
+		if err != nil {
+			return err
+		}
+		task := strings.Join(args[1:], " ")
+		if task == "" {
+			// The editor runs before taking the lock of the list,
+			// which the edit is then applied to as it is by then
+			if task, err = editTask(a.l.Items[i].Task); err != nil {
+				return err
+			}
+		}
+		return a.s.Update(func(l *todo.List) error {
+			return l.Edit(id, task)
+		})
+	}
+}
+
+// dependCommand returns the setup of the command adding or,
+// when remove is set, removing dependencies
+func dependCommand(remove bool) func(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(fs *flag.FlagSet) func(a *app, args []string) error {
+		return func(a *app, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			return a.s.Update(func(l *todo.List) error {
+				return dependOn(l, id, args[1], remove)
+			})
+		}
+	}
+}
+
+func statusCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		return a.s.Update(func(l *todo.List) error {
+			return l.SetStatus(id, args[1], a.c.Statuses())
+		})
+	}
+}
+
+func boardCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		items := a.l.In(a.active)
+		fmt.Print(items.Board(a.c.Statuses()))
+		return nil
+	}
+}
+
+func tuiCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		return runTUI(a)
+	}
+}
+
+func workflowCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		w, err := todo.ParseWorkflow(args[0])
+		if err != nil {
+			return err
+		}
+		return a.updateCatalog(func(c *todo.Catalog) error {
+			c.Workflow = w
+			return nil
+		})
+	}
+}
+
+func listsCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		for _, name := range append([]string{todo.DefaultList}, a.c.Names...) {
+			prefix := "  "
+			if name == a.active {
+				prefix = "* "
+			}
+			fmt.Printf("%s%s (%d)\n", prefix, name, len(a.l.In(name).Items))
+		}
+		return nil
+	}
+}
+
+func newListCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		return a.updateCatalog(func(c *todo.Catalog) error {
+			return c.Create(args[0])
+		})
+	}
+}
+
+func useCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		return a.updateCatalog(func(c *todo.Catalog) error {
+			c.Sync(a.l)
+			return c.Use(args[0])
+		})
+	}
+}
+
+func renameListCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		// Rename the list in the catalog first, which checks both names,
+		// and then move its items
+		return a.updateCatalog(func(c *todo.Catalog) error {
+			c.Sync(a.l)
+			if err := c.Rename(args[0], args[1]); err != nil {
+				return err
+			}
+			return a.s.Update(func(l *todo.List) error {
+				return l.RenameList(args[0], args[1])
+			})
+		})
+	}
+}
+
+func deleteListCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		return a.updateCatalog(func(c *todo.Catalog) error {
+			c.Sync(a.l)
+			if err := c.Remove(args[0]); err != nil {
+				return err
+			}
+			return a.s.Update(func(l *todo.List) error {
+				l.DeleteList(args[0])
+				return nil
+			})
+		})
+	}
+}
+
+func moveCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		if !a.c.Has(args[1]) {
+			return fmt.Errorf("%w: %q", todo.ErrListNotExists, args[1])
+		}
+		return a.s.Update(func(l *todo.List) error {
+			return l.Move(id, args[1])
+		})
+	}
+}
+
+func newTemplateCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	task := fs.String("task", "", "Task added along with the items, which become its subtasks")
+	return func(a *app, args []string) error {
+		items := args[1:]
+		if len(items) == 0 {
+			t, err := getTask(os.Stdin)
+			if err != nil {
+				return err
+			}
+			items = strings.Split(strings.TrimSuffix(t, "\n"), "\n")
+		}
+		return a.updateCatalog(func(c *todo.Catalog) error {
+			return c.AddTemplate(args[0], todo.Template{Task: *task, Items: items})
+		})
+	}
+}
+
+func templatesCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		names := a.c.TemplateNames()
+		if len(args) > 0 {
+			names = args
+		}
+		for _, name := range names {
+			t, err := a.c.Template(name)
+			if err != nil {
+				return err
+			}
+			fmt.Print(name)
+			if params := t.Params(); len(params) > 0 {
+				fmt.Printf(" (%s)", strings.Join(params, ", "))
+			}
+			fmt.Println(":")
+			for _, line := range strings.Split(strings.TrimSuffix(t.String(), "\n"), "\n") {
+				fmt.Printf("  %s\n", line)
+			}
+		}
+		return nil
+	}
+}
+
+func instantiateCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	due := fs.String("due", "", "Due date of the items, i.e. \"next friday\"")
+	tags := fs.String("tags", "", "Comma separated tags of the items")
+	return func(a *app, args []string) error {
+		t, err := a.c.Template(args[0])
+		if err != nil {
+			return err
+		}
+		params, err := todo.ParseParams(args[1:])
+		if err != nil {
+			return err
+		}
+		return a.s.Update(func(l *todo.List) error {
+			ids, err := l.Instantiate(t, params)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if err := l.Move(id, a.active); err != nil {
+					return err
+				}
+				if err := plan(l, id, *due, "", *tags, ""); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+func deleteTemplateCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		return a.updateCatalog(func(c *todo.Catalog) error {
+			return c.RemoveTemplate(args[0])
+		})
+	}
+}
+
+func archiveCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	older := fs.Int("older", 0, "Number of days since completion of the tasks to archive")
+	return func(a *app, args []string) error {
+		var n int
+		err := archive(a.s, a.a, func(l, archived *todo.List) error {
+			n = l.Archive(archived, a.active, time.Now().AddDate(0, 0, -*older))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Archived %d tasks\n", n)
+		return nil
+	}
+}
+
+func autoArchiveCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		days, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("%w: invalid number of days %q", errUsage, args[0])
+		}
+		return a.updateCatalog(func(c *todo.Catalog) error {
+			c.Sync(a.l)
+			return c.SetArchiveAfter(a.active, days)
+		})
+	}
+}
+
+func restoreCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		restored := id
+		err = archive(a.s, a.a, func(l, archived *todo.List) error {
+			var err error
+			restored, err = l.Restore(archived, id)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if restored != id {
+			fmt.Printf("Restored as %d\n", restored)
+		}
+		return nil
+	}
+}
+
+func reportCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	periods := fs.Int("periods", 8, "Number of days or weeks covered by the report")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	return func(a *app, args []string) error {
+		period := todo.PeriodWeek
+		if len(args) > 0 {
+			period = args[0]
+		}
+		// Archived tasks are part of the history of the list
+		archived := &todo.List{}
+		if err := a.a.Load(archived); err != nil {
+			return err
+		}
+		items := a.l.In(a.active)
+		items.Items = append(items.Items, archived.In(a.active).Items...)
+		r, err := items.Report(period, *periods, 5, time.Now())
+		if err != nil {
+			return err
+		}
+		if !*asJSON {
+			fmt.Print(r)
+			return nil
+		}
+		return json.NewEncoder(os.Stdout).Encode(r)
+	}
+}
+
+func importCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	sync := fs.Bool("sync", false, "Update the tasks with the IDs in the file, exported from this list, instead of adding them as new tasks")
+	return func(a *app, args []string) error {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		items, err := todo.ParseTodoTxt(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		var dropped []todo.Conflict
+		err = a.s.Update(func(l *todo.List) error {
+			if *sync {
+				dropped = l.Sync(items)
+				return nil
+			}
+			dropped = l.Import(items)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, c := range dropped {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", c)
+		}
+		return nil
+	}
+}
+
+func exportCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		name := "-"
+		if len(args) > 0 {
+			name = args[0]
+		}
+		return export(a.l, name)
+	}
+}
+
+// undoCommand returns the setup of the command undoing
+// or, when redo is set, redoing the last change
+func undoCommand(redo bool) func(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(fs *flag.FlagSet) func(a *app, args []string) error {
+		return func(a *app, args []string) error {
+			if redo {
+				e, err := a.s.Redo()
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Redone: %s\n", e)
+				return nil
+			}
+			e, err := a.s.Undo()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Undone: %s\n", e)
+			return nil
+		}
+	}
+}
+
+func historyCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		h, err := a.s.History()
+		if err != nil {
+			return err
+		}
+		for _, e := range h {
+			fmt.Println(e)
+		}
+		return nil
+	}
+}
+
+func passwdCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		return changePassphrase(a.file)
+	}
+}
+
+func mergeCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		conflicts, err := mergeFiles(args[0], args[1], args[2])
+		if err != nil {
+			return err
+		}
+		for _, c := range conflicts {
+			fmt.Fprintf(os.Stderr, "conflict: %s\n", c)
+		}
+		// A non zero exit status tells git the merge has conflicts
+		if len(conflicts) > 0 {
+			return fmt.Errorf("%w in %d tasks", errConflict, len(conflicts))
+		}
+		return nil
+	}
+}
+
+func completionCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		return writeCompletion(os.Stdout, args[0])
+	}
+}
+
+func helpCommand(fs *flag.FlagSet) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		if len(args) == 0 {
+			usage(os.Stdout)
+			return nil
+		}
+		c, ok := findCommand(args[0])
+		if !ok {
+			return fmt.Errorf("%w: unknown command %q", errUsage, args[0])
+		}
+		cfs, _ := c.newFlagSet()
+		cfs.SetOutput(os.Stdout)
+		cfs.Usage()
+		return nil
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// shells lists the shells with a completion script
+var shells = []string{"bash", "zsh", "fish"}
+
+// completion describes a command for the completion scripts
+type completion struct {
+	command
+	flags []*flag.Flag
+}
+
+// completions returns the commands along with their flags. help
+// completes the names of the commands
+func completions() []completion {
+	var names []string
+	for _, c := range commands() {
+		names = append(names, c.name)
+	}
+	var cs []completion
+	for _, c := range commands() {
+		fs, _ := c.newFlagSet()
+		cc := completion{command: c}
+		fs.VisitAll(func(f *flag.Flag) {
+			cc.flags = append(cc.flags, f)
+		})
+		if c.name == "help" {
+			cc.values = names
+		}
+		cs = append(cs, cc)
+	}
+	return cs
+}
+
+// writeCompletion writes the completion script of the shell to w
+func writeCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		writeBash(w, completions())
+	case "zsh":
+		writeZsh(w, completions())
+	case "fish":
+		writeFish(w, completions())
+	default:
+		return fmt.Errorf("%w: unknown shell %q: use %s", errUsage, shell, strings.Join(shells, ", "))
+	}
+	return nil
+}
+
+// flagNames returns the names of the flags of c with their dash
+func (c completion) flagNames() string {
+	var names []string
+	for _, f := range c.flags {
+		names = append(names, "-"+f.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+func writeBash(w io.Writer, cs []completion) {
+	var names []string
+	for _, c := range cs {
+		names = append(names, c.name)
+	}
+	fmt.Fprintf(w, "# bash completion for todo\n_todo() {\n")
+	fmt.Fprintf(w, "\tlocal cur=${COMP_WORDS[COMP_CWORD]} flags= values=\n")
+	fmt.Fprintf(w, "\tif [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn\n\tfi\n", strings.Join(names, " "))
+	fmt.Fprintf(w, "\tcase ${COMP_WORDS[1]} in\n")
+	for _, c := range cs {
+		fmt.Fprintf(w, "\t%s) flags=%q values=%q ;;\n", c.name, c.flagNames(), strings.Join(c.values, " "))
+	}
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "\tif [[ $cur == -* ]]; then\n\t\tCOMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "\telif [ -n \"$values\" ]; then\n\t\tCOMPREPLY=($(compgen -W \"$values\" -- \"$cur\"))\n\tfi\n}\n")
+	fmt.Fprintf(w, "complete -o default -F _todo todo\n")
+}
+
+func writeZsh(w io.Writer, cs []completion) {
+	fmt.Fprintf(w, "#compdef todo\n_todo() {\n\tlocal -a commands flags values\n\tcommands=(\n")
+	for _, c := range cs {
+		fmt.Fprintf(w, "\t\t%s\n", quote(c.name+":"+c.summary))
+	}
+	fmt.Fprintf(w, "\t)\n\tif (( CURRENT == 2 )); then\n\t\t_describe command commands\n\t\treturn\n\tfi\n")
+	fmt.Fprintf(w, "\tcase $words[2] in\n")
+	for _, c := range cs {
+		fmt.Fprintf(w, "\t%s) flags=(%s) values=(%s) ;;\n", c.name, c.flagNames(), strings.Join(c.values, " "))
+	}
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "\tif [[ $PREFIX == -* ]]; then\n\t\tcompadd -a flags\n")
+	fmt.Fprintf(w, "\telif (( $#values )); then\n\t\tcompadd -a values\n\telse\n\t\t_files\n\tfi\n}\n")
+	fmt.Fprintf(w, "compdef _todo todo\n")
+}
+
+func writeFish(w io.Writer, cs []completion) {
+	fmt.Fprintf(w, "# fish completion for todo\ncomplete -c todo -f\n")
+	for _, c := range cs {
+		fmt.Fprintf(w, "complete -c todo -n __fish_use_subcommand -a %s -d %s\n", c.name, quote(c.summary))
+	}
+	for _, c := range cs {
+		cond := quote("__fish_seen_subcommand_from " + c.name)
+		for _, f := range c.flags {
+			fmt.Fprintf(w, "complete -c todo -n %s -o %s -d %s\n", cond, f.Name, quote(f.Usage))
+		}
+		if len(c.values) > 0 {
+			fmt.Fprintf(w, "complete -c todo -n %s -a %s\n", cond, quote(strings.Join(c.values, " ")))
+		}
+		if c.files {
+			fmt.Fprintf(w, "complete -c todo -n %s -F\n", cond)
+		}
+	}
+}
+
+// quote quotes s for the shell with single quotes
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// config holds the settings of the config file. Its lines are
+// key = value pairs, with # starting comments. The keys are:
+//
+//   - file: the file of the tasks, overridden by TODO_FILENAME
+//   - store: the storage backend, overridden by TODO_STORE
+//   - COMMAND.FLAG: the default of a flag of a command,
+//     i.e. report.periods = 4
+type config struct {
+	values map[string]string
+	// flags maps command names to the defaults of their flags
+	flags map[string]map[string]string
+}
+
+// configPath returns the path of the config file, set with the
+// environment variable TODO_CONFIG or in the user config directory
+func configPath() string {
+	if name := os.Getenv("TODO_CONFIG"); name != "" {
+		return name
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "todo", "config")
+}
+
+// loadConfig reads the config file. A missing file is an empty config
+func loadConfig() (*config, error) {
+	cfg := &config{values: map[string]string{}, flags: map[string]map[string]string{}}
+	name := configPath()
+	if name == "" {
+		return cfg, nil
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := cfg.set(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, n, err)
+		}
+	}
+	return cfg, s.Err()
+}
+
+// set parses a key = value line of the config file
+func (cfg *config) set(line string) error {
+	key, value, ok := strings.Cut(line, "=")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !ok || key == "" {
+		return fmt.Errorf("expected key = value, got %q", line)
+	}
+	switch key {
+	case "file", "store":
+		cfg.values[key] = value
+		return nil
+	}
+	name, flagName, ok := strings.Cut(key, ".")
+	c, found := findCommand(name)
+	if !ok || !found {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	fs, _ := c.newFlagSet()
+	if fs.Lookup(flagName) == nil {
+		return fmt.Errorf("unknown setting %q: %s has no flag -%s", key, name, flagName)
+	}
+	if cfg.flags[name] == nil {
+		cfg.flags[name] = map[string]string{}
+	}
+	cfg.flags[name][flagName] = value
+	return nil
+}
+
+// setDefaults sets the flags of the command to the values of the config file
+func (cfg *config) setDefaults(name string, fs *flag.FlagSet) error {
+	for flagName, value := range cfg.flags[name] {
+		if err := fs.Set(flagName, value); err != nil {
+			return fmt.Errorf("invalid setting %s.%s in %s: %w", name, flagName, configPath(), err)
+		}
+		fs.Lookup(flagName).DefValue = value
+	}
+	return nil
+}
+
+// store returns the storage backend from TODO_STORE or the config file
+func (cfg *config) store() string {
+	if kind := os.Getenv("TODO_STORE"); kind != "" {
+		return kind
+	}
+	return cfg.values["store"]
+}
+
+// file returns the file of the tasks from TODO_FILENAME or the config
+// file, defaulting to a file in the current directory named after the
+// storage backend
+func (cfg *config) file() string {
+	if name := os.Getenv("TODO_FILENAME"); name != "" {
+		return name
+	}
+	if name := cfg.values["file"]; name != "" {
+		if rest, ok := strings.CutPrefix(name, "~/"); ok {
+			if home, err := os.UserHomeDir(); err == nil {
+				return filepath.Join(home, rest)
+			}
+		}
+		return name
+	}
+	if cfg.store() == "sqlite3" {
+		return todoDBName
+	}
+	return todoFileName
+}
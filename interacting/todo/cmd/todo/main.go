@@ -2,128 +2,348 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"pragprog.com/rggo/interacting/todo"
+	"strconv"
 	"strings"
 	"time"
 )
 
-var todoFileName = ".todo.json"
+var (
+	todoFileName = ".todo.json"
+	todoDBName   = ".todo.db"
+)
+
+// Exit codes
+const (
+	exitOK       = 0
+	exitFailure  = 1
+	exitUsage    = 2
+	exitConflict = 3
+)
+
+var (
+	errUsage    = errors.New("invalid usage")
+	errConflict = errors.New("merge conflicts")
+)
 
 func main() {
-	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(),
-			"%s tool. Developed for The Pragmatic Bookshelf\n", os.Args[0])
-		fmt.Fprintf(flag.CommandLine.Output(), "Copyright 2020\n")
-		fmt.Fprintln(flag.CommandLine.Output(), "To add new task, use -add flag followed by task name. You can also provide task name from STDIN.")
-		fmt.Fprintln(flag.CommandLine.Output(), "The default filename to save the to-do tasks is .todo.json.\nTo change the filename, specify the new filename with environment variable TODO_FILENAME.\ni.e. `export TODO_FILENAME=<new filename>`")
-		fmt.Fprintln(flag.CommandLine.Output(), "Usage information:")
-		flag.PrintDefaults()
+	os.Exit(run(os.Args[1:]))
+}
+
+// run runs the command given by args and returns the exit code
+func run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return exitUsage
+	}
+	c, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		usage(os.Stderr)
+		return exitUsage
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
 	}
 
-	// Parsing command line flags
-	add := flag.Bool("add", false, "Add task to the to-do list")
-	list := flag.Bool("list", false, "List all tasks")
-	complete := flag.Int("complete", 0, "Item to be completed")
-	del := flag.Int("delete", 0, "Item to be deleted")
-	verbose := flag.Bool("verbose", false, "Enable verbose output")
-	filter := flag.Bool("filter", false, "Prevent displaying completed itmes")
+	// Parsing the command line flags, which default to the config file
+	fs, runCommand := c.newFlagSet()
+	if err := cfg.setDefaults(c.name, fs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() < c.min || (c.max >= 0 && fs.NArg() > c.max) {
+		fmt.Fprintf(os.Stderr, "wrong number of arguments for %s\n", c.name)
+		fs.Usage()
+		return exitUsage
+	}
 
-	flag.Parse()
+	a := &app{file: cfg.file(), store: cfg.store()}
+	if !c.noStore {
+		if err := a.open(!c.noArchive); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		defer a.close()
+	}
 
-	if os.Getenv("TODO_FILENAME") != "" {
-		todoFileName = os.Getenv("TODO_FILENAME")
+	err = runCommand(a, fs.Args())
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(os.Stderr, err)
+		fs.Usage()
+		return exitUsage
+	case errors.Is(err, errConflict):
+		fmt.Fprintln(os.Stderr, err)
+		return exitConflict
 	}
+	fmt.Fprintln(os.Stderr, err)
+	return exitFailure
+}
 
-	// Define an items list
-	l := &todo.List{}
+// usage writes the usage of the tool to w
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "todo tool. Developed for The Pragmatic Bookshelf\n")
+	fmt.Fprintf(w, "Copyright 2020\n\n")
+	fmt.Fprintf(w, "Usage: todo COMMAND [flags] [arguments]\n\nCommands:\n")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-13s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "\nRun `todo help COMMAND` for the flags of a command. Flags go before the arguments.")
+	fmt.Fprintln(w, "The default filename to save the to-do tasks is .todo.json.\nTo change the filename, specify the new filename with environment variable TODO_FILENAME.\ni.e. `export TODO_FILENAME=<new filename>`")
+	fmt.Fprintln(w, "To keep the to-do tasks in a SQLite database (.todo.db by default), set environment variable TODO_STORE=sqlite3.")
+	fmt.Fprintf(w, "Both can be set in the config file %s, along with defaults of the command flags. Set TODO_CONFIG to use another file.\n", configPath())
+	fmt.Fprintln(w, "Tasks are kept in named lists. New tasks go to the active list, which is the default list until another one is selected with use.")
+	fmt.Fprintln(w, "Templates hold tasks added together, such as the steps of a release. Their parameters such as {version} get values with instantiate NAME version=1.2.")
+	fmt.Fprintln(w, "The passphrase of encrypted tasks is read from environment variable TODO_PASSPHRASE or prompted for, and passwd reads the new one from TODO_NEW_PASSPHRASE.")
+	fmt.Fprintln(w, "To merge the tasks in git, configure the merge driver with `git config merge.todo.driver \"todo merge %O %A %B\"` and add `.todo.json merge=todo` to .gitattributes.")
+	fmt.Fprintf(w, "Exit status: %d on success, %d on failure, %d on invalid usage and %d when merge leaves conflicts.\n", exitOK, exitFailure, exitUsage, exitConflict)
+}
 
-	// Use the Get method to read to-do items from file
-	if err := l.Get(todoFileName); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+// app holds the stores of the list and its archive, the catalog of
+// lists and the list loaded when the command starts
+type app struct {
+	file  string
+	store string
+
+	s           *todo.Journal
+	a           todo.Store
+	c           *todo.Catalog
+	catalogName string
+	catalogOpt  todo.Option
+	l           *todo.List
+	active      string
+}
+
+// open opens the stores, archives the items completed long enough ago
+// in the lists configured with auto-archive when autoArchive is set,
+// and loads the list
+func (a *app) open(autoArchive bool) error {
+	passphrase, err := getPassphrase(a.file, "TODO_PASSPHRASE")
+	if err != nil {
+		return err
+	}
+	if a.s, err = getStore(a.store, a.file, passphrase); err != nil {
+		return err
+	}
+	if a.a, err = getArchive(a.store, a.file, passphrase); err != nil {
+		a.s.Close()
+		return err
 	}
 
-	// Decide what to do based on the number of arguments provided
-	switch {
-	// For no extra arguments, print the list
-	case *list:
-		// List current to-do items
-		fmt.Print(l)
-	// Enable verbose output
-	case *verbose:
-		// List current to-do items with time of creation and completion
-		var formatted string
-		for index, value := range *l {
-			prefix := "  "
-			suffix := fmt.Sprintf("Created at: %s\n", value.CreatedAt.Format(time.DateTime))
-			if value.Done {
-				prefix = "X "
-				suffix = fmt.Sprintf("Created at: %s, Completed at: %s\n", value.CreatedAt.Format(time.DateTime), value.CompletedAt.Format(time.DateTime))
-			}
-			formatted += fmt.Sprintf("%s%d: %s, %s", prefix, index+1, value.Task, suffix)
-		}
-		fmt.Print(formatted)
-	// Display uncompleted tasks only
-	case *filter:
-		var formatted string
-		for index, value := range *l {
-			if value.Done {
-				continue
+	// Read the list names, the active list and the archive settings,
+	// encrypted along with the list
+	a.catalogName, a.catalogOpt = a.file+".lists", todo.WithPassphrase(passphrase)
+	a.c = &todo.Catalog{}
+	if err := a.c.Get(a.catalogName, a.catalogOpt); err != nil {
+		a.close()
+		return err
+	}
+	if autoArchive && len(a.c.ArchiveAfter) > 0 {
+		// Archiving is not a change of the user, so it bypasses the
+		// journal and undo reverts the change of the command instead
+		err := archive(a.s.Store, a.a, func(l, archived *todo.List) error {
+			for name, days := range a.c.ArchiveAfter {
+				l.Archive(archived, name, time.Now().AddDate(0, 0, -days))
 			}
-			formatted += fmt.Sprintf("  %d: %s\n", index+1, value.Task)
+			return nil
+		})
+		if err != nil {
+			a.close()
+			return err
 		}
-		fmt.Print(formatted)
-	// Complete the given item
-	case *complete > 0:
-		// Complete the given item
-		if err := l.Complete(*complete); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+	}
+
+	a.l = &todo.List{}
+	if err := a.s.Load(a.l); err != nil {
+		a.close()
+		return err
+	}
+	a.c.Sync(a.l)
+	a.active = a.c.Current()
+	return nil
+}
+
+// updateCatalog applies fn to the catalog of lists while holding its lock
+func (a *app) updateCatalog(fn func(*todo.Catalog) error) error {
+	return todo.UpdateCatalog(a.catalogName, fn, a.catalogOpt)
+}
+
+// close closes the stores
+func (a *app) close() {
+	a.s.Close()
+	a.a.Close()
+}
+
+// describe formats the items with their time of creation and completion
+func describe(items todo.List) string {
+	var formatted string
+	for _, value := range items.Items {
+		prefix := "  "
+		suffix := fmt.Sprintf("Created at: %s\n", value.CreatedAt.Format(time.DateTime))
+		if value.Done() {
+			prefix = "X "
+			suffix = fmt.Sprintf("Created at: %s, Completed at: %s\n", value.CreatedAt.Format(time.DateTime), value.CompletedAt.Format(time.DateTime))
+		}
+		if !value.Due.IsZero() {
+			suffix = fmt.Sprintf("Due at: %s, %s", value.Due.Format(time.DateTime), suffix)
+		}
+		if value.Recur != nil {
+			suffix = fmt.Sprintf("Repeats: %s, %s", value.Recur, suffix)
 		}
+		formatted += fmt.Sprintf("%s%d: %s, %s", prefix, value.ID, value.Task, suffix)
+	}
+	return formatted
+}
 
-		// Save the new list
-		if err := l.Save(todoFileName); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+// archive applies fn to the list in s and the archive in a while
+// holding the lock of both. The archive is saved first, so a failure
+// leaves items in both rather than in none
+func archive(s, a todo.Store, fn func(l, archived *todo.List) error) error {
+	return s.Update(func(l *todo.List) error {
+		return a.Update(func(archived *todo.List) error {
+			return fn(l, archived)
+		})
+	})
+}
+
+// mergeFiles merges the lists in the files ours and theirs using the
+// list in base as their common ancestor and saves the result to ours
+func mergeFiles(base, ours, theirs string) ([]todo.Conflict, error) {
+	passphrase, err := getPassphrase(ours, "TODO_PASSPHRASE")
+	if err != nil {
+		return nil, err
+	}
+	opt := todo.WithPassphrase(passphrase)
+	var lists [3]todo.List
+	for i, name := range []string{base, ours, theirs} {
+		if err := lists[i].Get(name, opt); err != nil {
+			return nil, err
 		}
-	case *add:
-		// When any arguments (excluding flags) are provided,
-		// they will be used as the new task
-		t, err := getTask(os.Stdin, flag.Args()...)
+	}
+	merged, conflicts, err := todo.Merge(lists[0], lists[1], lists[2])
+	if err != nil {
+		return nil, err
+	}
+	return conflicts, merged.Save(ours, opt)
+}
+
+// changePassphrase encrypts the file name, its journal, its archive and its
+// catalog of lists with a new passphrase, or decrypts them when the new
+// passphrase is empty
+func changePassphrase(name string) error {
+	old, err := getPassphrase(name, "TODO_PASSPHRASE")
+	if err != nil {
+		return err
+	}
+	new, err := getNewPassphrase("TODO_NEW_PASSPHRASE")
+	if err != nil {
+		return err
+	}
+	// The catalog has a lock of its own, taken before the one of the
+	// list as when deleting a list, and the files change all together
+	return todo.ChangePassphrases(old, new, []string{name + ".lists"},
+		[]string{name, name + ".journal", name + ".archive"})
+}
+
+// export writes the list in the todo.txt format to the file name
+func export(l *todo.List, name string) error {
+	if name == "-" {
+		return l.WriteTodoTxt(os.Stdout)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := l.WriteTodoTxt(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// plan sets the optional due date, priority, tags and recurrence on a new item
+func plan(l *todo.List, id int, due, priority, tags, recur string) error {
+	if due != "" {
+		d, err := parseDue(due)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			return err
 		}
-		s := strings.Split(t, "\n")
-		for _, task := range s[:len(s)-1] {
-			l.Add(task)
+		if err := l.SetDue(id, d); err != nil {
+			return err
 		}
-		// Save the new list
-		if err := l.Save(todoFileName); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+	}
+	if priority != "" {
+		if err := l.SetPriority(id, priority); err != nil {
+			return err
 		}
-	case *del > 0:
-		// Delete the given item
-		if err := l.Delete(*del); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+	}
+	if tags != "" {
+		if err := l.AddTags(id, strings.Split(tags, ",")...); err != nil {
+			return err
 		}
+	}
+	if recur != "" {
+		r, err := todo.ParseRecurrence(recur)
+		if err != nil {
+			return err
+		}
+		if err := l.SetRecurrence(id, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		// Save the new list
-		if err := l.Save(todoFileName); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+// dependOn adds or, when remove is set, removes the dependencies of
+// the item with the given ID on the comma separated IDs in deps
+func dependOn(l *todo.List, id int, deps string, remove bool) error {
+	if deps == "" {
+		return nil
+	}
+	for _, f := range strings.Split(deps, ",") {
+		dep, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return fmt.Errorf("%w: %q", todo.ErrInvalidDep, f)
+		}
+		if remove {
+			err = l.RemoveDependency(id, dep)
+		} else {
+			err = l.AddDependency(id, dep)
+		}
+		if err != nil {
+			return err
 		}
-	default:
-		// Invalid flag provided
-		flag.Usage()
-		os.Exit(1)
 	}
+	return nil
+}
+
+// parseDue parses a due date expression, such as "next friday 3pm".
+// A date without time of day is due by the end of that day
+func parseDue(s string) (time.Time, error) {
+	d, timed, err := todo.ParseDate(s, time.Now())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid due date: %w", err)
+	}
+	if !timed {
+		d = d.AddDate(0, 0, 1).Add(-time.Second)
+	}
+	return d, nil
 }
 
 // getTask function decides where to get the description for a new
@@ -1,13 +1,17 @@
 package main_test
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"pragprog.com/rggo/interacting/todo"
+	"strings"
 	"testing"
 	"time"
 )
@@ -49,6 +53,11 @@ func TestMain(m *testing.M) {
 		fmt.Fprintf(os.Stderr, "failed to remove a file %s", fileName)
 		os.Exit(1)
 	}
+	os.Remove(fileName + ".lock")
+	os.Remove(fileName + ".journal")
+	os.Remove(fileName + ".lists")
+	os.Remove(fileName + ".lists.lock")
+	os.Remove(fileName + ".archive")
 
 	os.Exit(result)
 }
@@ -63,7 +72,7 @@ func TestTodoCLI(t *testing.T) {
 
 	task := "test task number 1"
 	t.Run("AddNewTask", func(t *testing.T) {
-		cmd := exec.Command(cmdPath, "-add", task)
+		cmd := exec.Command(cmdPath, "add", task)
 		if err := cmd.Run(); err != nil {
 			t.Fatal(err)
 		}
@@ -71,7 +80,7 @@ func TestTodoCLI(t *testing.T) {
 
 	task2 := "test task number 2"
 	t.Run("AddNewTaskFromSTDIN", func(t *testing.T) {
-		cmd := exec.Command(cmdPath, "-add")
+		cmd := exec.Command(cmdPath, "add")
 		cmdStdin, err := cmd.StdinPipe()
 		if err != nil {
 			t.Fatal(err)
@@ -85,7 +94,7 @@ func TestTodoCLI(t *testing.T) {
 		}
 	})
 	t.Run("ListTasks", func(t *testing.T) {
-		cmd := exec.Command(cmdPath, "-list")
+		cmd := exec.Command(cmdPath, "list")
 		out, err := cmd.CombinedOutput()
 		if err != nil {
 			t.Fatal(err)
@@ -94,11 +103,11 @@ func TestTodoCLI(t *testing.T) {
 		assert.Equal(t, expected, string(out), "expected %q, got %q", expected, string(out))
 	})
 	t.Run("CompleteTask", func(t *testing.T) {
-		cmd := exec.Command(cmdPath, "-complete", "1")
+		cmd := exec.Command(cmdPath, "complete", "1")
 		if err := cmd.Run(); err != nil {
 			t.Fatal(err)
 		}
-		cmd = exec.Command(cmdPath, "-list")
+		cmd = exec.Command(cmdPath, "list")
 		out, err := cmd.CombinedOutput()
 		if err != nil {
 			t.Fatal(err)
@@ -107,11 +116,11 @@ func TestTodoCLI(t *testing.T) {
 		assert.Equal(t, expected, string(out), "expected %q, got %q", expected, string(out))
 	})
 	t.Run("DeleteTask", func(t *testing.T) {
-		cmd := exec.Command(cmdPath, "-delete", "2")
+		cmd := exec.Command(cmdPath, "delete", "2")
 		if err := cmd.Run(); err != nil {
 			t.Fatal(err)
 		}
-		cmd = exec.Command(cmdPath, "-list")
+		cmd = exec.Command(cmdPath, "list")
 		out, err := cmd.CombinedOutput()
 		if err != nil {
 			t.Fatal(err)
@@ -120,11 +129,11 @@ func TestTodoCLI(t *testing.T) {
 		assert.Equal(t, expected, string(out), "expected %q, got %q", expected, string(out))
 	})
 	t.Run("DelteTaskAgain", func(t *testing.T) {
-		cmd := exec.Command(cmdPath, "-delete", "1")
+		cmd := exec.Command(cmdPath, "delete", "1")
 		if err := cmd.Run(); err != nil {
 			t.Fatal(err)
 		}
-		cmd = exec.Command(cmdPath, "-list")
+		cmd = exec.Command(cmdPath, "list")
 		out, err := cmd.CombinedOutput()
 		if err != nil {
 			t.Fatal(err)
@@ -134,31 +143,31 @@ func TestTodoCLI(t *testing.T) {
 	})
 	task3 := "test task number 3"
 	t.Run("VerboseOutput", func(t *testing.T) {
-		cmd := exec.Command(cmdPath, "-add", task3)
+		cmd := exec.Command(cmdPath, "add", task3)
 		if err := cmd.Run(); err != nil {
 			t.Fatal(err)
 		}
-		cmd = exec.Command(cmdPath, "-verbose")
+		cmd = exec.Command(cmdPath, "list", "-verbose")
 		out, err := cmd.CombinedOutput()
 		if err != nil {
 			t.Fatal(err)
 		}
-		expected := fmt.Sprintf("  1: test task number 3, Created at: %s\n", time.Now().Format(time.DateTime))
+		expected := fmt.Sprintf("  3: test task number 3, Created at: %s\n", time.Now().Format(time.DateTime))
 		assert.Equal(t, expected, string(out), "expected %q, got %q", expected, string(out))
 	})
 	t.Run("FilteredOutput", func(t *testing.T) {
-		cmd := exec.Command(cmdPath, "-filter")
+		cmd := exec.Command(cmdPath, "list", "-open")
 		out, err := cmd.CombinedOutput()
 		if err != nil {
 			t.Fatal(err)
 		}
-		expected := fmt.Sprintf("  1: test task number 3\n")
+		expected := fmt.Sprintf("  3: test task number 3\n")
 		assert.Equal(t, expected, string(out), "expected %q, got %q", expected, string(out))
-		cmd = exec.Command(cmdPath, "-complete", "1")
+		cmd = exec.Command(cmdPath, "complete", "3")
 		if err := cmd.Run(); err != nil {
 			t.Fatal(err)
 		}
-		cmd = exec.Command(cmdPath, "-filter")
+		cmd = exec.Command(cmdPath, "list", "-open")
 		out, err = cmd.CombinedOutput()
 		if err != nil {
 			t.Fatal(err)
@@ -166,4 +175,998 @@ func TestTodoCLI(t *testing.T) {
 		expected = ""
 		assert.Equal(t, expected, string(out), "expected %q, got %q", expected, string(out))
 	})
+	task4 := "test task number 4"
+	t.Run("AddPlannedTask", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "add", "-priority", "a", "-due", "2000-01-01", "-tags", "work,home", task4)
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		cmd = exec.Command(cmdPath, "list")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := fmt.Sprintf("  4: (A) %s #home #work (due 2000-01-01) [overdue]\nX 3: test task number 3\n", task4)
+		assert.Equal(t, expected, string(out), "expected %q, got %q", expected, string(out))
+	})
+	t.Run("AddInvalidPriority", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "add", "-priority", "AB", "invalid task")
+		if err := cmd.Run(); err == nil {
+			t.Fatal("expected error for invalid priority, got none")
+		}
+	})
+}
+
+func TestTodoCLISQLite3(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := append(os.Environ(),
+		"TODO_STORE=sqlite3",
+		"TODO_FILENAME="+filepath.Join(t.TempDir(), "todo.db"))
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return string(out)
+	}
+
+	t.Run("AddCompleteDelete", func(t *testing.T) {
+		run(t, "add", "sqlite task 1")
+		run(t, "add", "sqlite task 2")
+		run(t, "complete", "2")
+		run(t, "delete", "1")
+		expected := "X 2: sqlite task 2\n"
+		out := run(t, "list")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("Archive", func(t *testing.T) {
+		out := run(t, "archive")
+		assert.Equal(t, "Archived 1 tasks\n", out)
+		expected := "X 2: sqlite task 2\n"
+		out = run(t, "list", "-archived")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+}
+
+func TestTodoCLIUndo(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), ".todo.json"))
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return string(out)
+	}
+
+	run(t, "add", "undo task 1")
+	run(t, "add", "undo task 2")
+	run(t, "delete", "1")
+	t.Run("Undo", func(t *testing.T) {
+		out := run(t, "undo")
+		assert.Contains(t, out, "delete 1: undo task 1")
+		expected := "  1: undo task 1\n  2: undo task 2\n"
+		out = run(t, "list")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("Redo", func(t *testing.T) {
+		run(t, "redo")
+		expected := "  2: undo task 2\n"
+		out := run(t, "list")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("History", func(t *testing.T) {
+		out := run(t, "history")
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if len(lines) != 5 {
+			t.Fatalf("expected 5 history entries, got %q", out)
+		}
+		assert.Contains(t, lines[3], "undo #3")
+		assert.Contains(t, lines[4], "redo #3")
+	})
+}
+
+func TestTodoCLIRecurring(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), ".todo.json"))
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return string(out)
+	}
+
+	due := time.Now().AddDate(0, 0, 1)
+	run(t, "add", "-recur", "daily", "-due", due.Format(time.DateOnly), "water plants")
+	run(t, "complete", "1")
+	out := run(t, "list", "-verbose")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected the next occurrence to be added, got %q", out)
+	}
+	expNext := due.AddDate(0, 0, 1).Format(time.DateOnly) + " 23:59:59"
+	assert.Contains(t, lines[1], "  2: water plants, Repeats: daily, Due at: "+expNext)
+}
+
+func TestTodoCLISubtasks(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), ".todo.json"))
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return string(out)
+	}
+
+	run(t, "add", "release")
+	run(t, "add", "-parent", "1", "tag")
+	run(t, "add", "-parent", "1", "announce")
+	t.Run("Tree", func(t *testing.T) {
+		run(t, "complete", "2")
+		expected := "  1: release [1/2]\nX   2: tag\n    3: announce\n"
+		out := run(t, "list")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("CompleteChildren", func(t *testing.T) {
+		run(t, "complete", "-children", "1")
+		expected := "X 1: release [2/2]\nX   2: tag\nX   3: announce\n"
+		out := run(t, "list")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("DeleteSubtree", func(t *testing.T) {
+		run(t, "delete", "1")
+		out := run(t, "list")
+		assert.Equal(t, "", out, "expected empty list, got %q", out)
+	})
+}
+
+func TestTodoCLITodoTxt(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	tmp := t.TempDir()
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(tmp, ".todo.json"))
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return string(out)
+	}
+
+	todoTxt := filepath.Join(tmp, "todo.txt")
+	if err := os.WriteFile(todoTxt, []byte("(B) Call vendor +work\nx Renew passport\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("Import", func(t *testing.T) {
+		run(t, "import", todoTxt)
+		expected := "  1: (B) Call vendor #work\nX 2: Renew passport\n"
+		out := run(t, "list")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("Export", func(t *testing.T) {
+		out := run(t, "export", "-")
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 lines, got %q", out)
+		}
+		today := time.Now().Format(time.DateOnly)
+		assert.True(t, strings.HasPrefix(lines[0], "(B) "+today+" Call vendor +work id:1 created:"), "unexpected line %q", lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "x Renew passport id:2 created:"), "unexpected line %q", lines[1])
+	})
+	edited := filepath.Join(tmp, "edited.txt")
+	if err := os.WriteFile(edited, []byte("x Call vendor id:1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("ImportNewIDs", func(t *testing.T) {
+		run(t, "import", edited)
+		expected := "  1: (B) Call vendor #work\nX 2: Renew passport\nX 3: Call vendor\n"
+		out := run(t, "list")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+		run(t, "delete", "3")
+	})
+	t.Run("Sync", func(t *testing.T) {
+		run(t, "import", "-sync", edited)
+		expected := "X 1: Call vendor\nX 2: Renew passport\n"
+		out := run(t, "list")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+}
+
+func TestTodoCLIQuery(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), ".todo.json"))
+
+	run := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	for _, args := range [][]string{
+		{"add", "-tags", "ops", "Deploy the API"},
+		{"add", "Deploy the website"},
+		{"add", "Write release notes"},
+		{"complete", "2"},
+	} {
+		if out, err := run(t, args...); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"OpenDeploy", "open and created>=-7d and deploy", "  1: Deploy the API #ops\n"},
+		{"Or", "tag:ops or done", "  1: Deploy the API #ops\nX 2: Deploy the website\n"},
+		{"Not", `not text~"^Deploy"`, "  3: Write release notes\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := run(t, "list", "-query", tc.query)
+			if err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+			assert.Equal(t, tc.expected, out, "expected %q, got %q", tc.expected, out)
+		})
+	}
+
+	t.Run("InvalidQuery", func(t *testing.T) {
+		out, err := run(t, "list", "-query", "due<someday")
+		assert.Error(t, err)
+		assert.Contains(t, out, "invalid query")
+	})
+}
+
+func TestTodoCLILists(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), ".todo.json"))
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return string(out)
+	}
+
+	run(t, "add", "Buy milk")
+	t.Run("NewList", func(t *testing.T) {
+		run(t, "new-list", "work")
+		expected := "* default (1)\n  work (0)\n"
+		out := run(t, "lists")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("Use", func(t *testing.T) {
+		run(t, "use", "work")
+		run(t, "add", "Write report")
+		expected := "  2: Write report\n"
+		out := run(t, "list")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("Move", func(t *testing.T) {
+		run(t, "move", "1", "work")
+		expected := "  default (0)\n* work (2)\n"
+		out := run(t, "lists")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("RenameList", func(t *testing.T) {
+		run(t, "rename-list", "work", "job")
+		expected := "default:\njob:\n  1: Buy milk\n  2: Write report\n"
+		out := run(t, "list", "-all")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("DeleteList", func(t *testing.T) {
+		run(t, "delete-list", "job")
+		expected := "* default (0)\n"
+		out := run(t, "lists")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("UnknownList", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "use", "home")
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		assert.Error(t, err)
+		assert.Contains(t, string(out), "list does not exist")
+	})
+}
+
+func TestTodoCLIEncryption(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	fileName := filepath.Join(t.TempDir(), ".todo.json")
+	env := append(os.Environ(), "TODO_FILENAME="+fileName)
+
+	run := func(t *testing.T, env []string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	if out, err := run(t, env, "add", "Call ACME Corp"); err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+	t.Run("Encrypt", func(t *testing.T) {
+		out, err := run(t, append(env, "TODO_NEW_PASSPHRASE=secret"), "passwd")
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.NotContains(t, string(data), "ACME", "expected the file to be encrypted")
+	})
+	t.Run("MissingPassphrase", func(t *testing.T) {
+		out, err := run(t, env, "list")
+		assert.Error(t, err)
+		assert.Contains(t, out, "TODO_PASSPHRASE")
+	})
+	t.Run("ListWithPassphrase", func(t *testing.T) {
+		out, err := run(t, append(env, "TODO_PASSPHRASE=secret"), "list")
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		assert.Equal(t, "  1: Call ACME Corp\n", out)
+	})
+	t.Run("EncryptedCatalog", func(t *testing.T) {
+		out, err := run(t, append(env, "TODO_PASSPHRASE=secret"), "new-list", "acme-deals")
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		data, err := os.ReadFile(fileName + ".lists")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.NotContains(t, string(data), "acme", "expected the catalog to be encrypted")
+	})
+	t.Run("Rotate", func(t *testing.T) {
+		out, err := run(t, append(env, "TODO_PASSPHRASE=secret", "TODO_NEW_PASSPHRASE=rotated"), "passwd")
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		_, err = run(t, append(env, "TODO_PASSPHRASE=secret"), "list")
+		assert.Error(t, err, "expected the old passphrase to be rejected")
+		out, err = run(t, append(env, "TODO_PASSPHRASE=rotated"), "undo")
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		assert.Contains(t, out, "Call ACME Corp", "expected the journal to be readable")
+		out, err = run(t, append(env, "TODO_PASSPHRASE=rotated"), "lists")
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		assert.Contains(t, out, "acme-deals", "expected the catalog to be readable")
+	})
+}
+
+func TestTodoCLIMerge(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	tmp := t.TempDir()
+	base := filepath.Join(tmp, "base.json")
+	ours := filepath.Join(tmp, "ours.json")
+	theirs := filepath.Join(tmp, "theirs.json")
+
+	run := func(t *testing.T, name string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = append(os.Environ(), "TODO_FILENAME="+name)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	must := func(t *testing.T, name string, args ...string) string {
+		t.Helper()
+		out, err := run(t, name, args...)
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return out
+	}
+	copyFile := func(t *testing.T, from, to string) {
+		t.Helper()
+		data, err := os.ReadFile(from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(to, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	must(t, base, "add", "Task 1")
+	must(t, base, "add", "Task 2")
+	copyFile(t, base, ours)
+	copyFile(t, base, theirs)
+
+	t.Run("Merge", func(t *testing.T) {
+		must(t, ours, "add", "Ours")
+		must(t, theirs, "complete", "1")
+		must(t, theirs, "add", "Theirs")
+		must(t, ours, "merge", base, ours, theirs)
+		expected := "X 1: Task 1\n  2: Task 2\n  3: Ours\n  4: Theirs\n"
+		out := must(t, ours, "list")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("Conflict", func(t *testing.T) {
+		copyFile(t, ours, base)
+		copyFile(t, ours, theirs)
+		// The CLI cannot edit tasks, so change task 2 on both sides directly
+		for _, name := range []string{ours, theirs} {
+			l := todo.List{}
+			if err := l.Get(name); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.SetPriority(2, map[string]string{ours: "A", theirs: "B"}[name]); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Save(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		out, err := run(t, ours, "merge", base, ours, theirs)
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+			t.Fatalf("expected exit status 3, got %v: %s", err, out)
+		}
+		assert.Contains(t, out, "conflict: 2: Task 2: both sides changed Priority")
+	})
+}
+
+func TestTodoCLIArchive(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	fileName := filepath.Join(t.TempDir(), ".todo.json")
+	env := append(os.Environ(), "TODO_FILENAME="+fileName)
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return string(out)
+	}
+
+	run(t, "add", "Task 1")
+	run(t, "add", "Task 2")
+	run(t, "complete", "1")
+
+	t.Run("Older", func(t *testing.T) {
+		out := run(t, "archive", "-older", "1")
+		assert.Equal(t, "Archived 0 tasks\n", out)
+	})
+	t.Run("Archive", func(t *testing.T) {
+		out := run(t, "archive")
+		assert.Equal(t, "Archived 1 tasks\n", out)
+		out = run(t, "list")
+		assert.Equal(t, "  2: Task 2\n", out)
+		out = run(t, "list", "-archived")
+		assert.Equal(t, "X 1: Task 1\n", out)
+	})
+	t.Run("Restore", func(t *testing.T) {
+		run(t, "restore", "1")
+		out := run(t, "list")
+		assert.Equal(t, "  2: Task 2\nX 1: Task 1\n", out)
+		out = run(t, "list", "-archived")
+		assert.Equal(t, "", out)
+	})
+	t.Run("ArchiveAfter", func(t *testing.T) {
+		run(t, "auto-archive", "1")
+		out := run(t, "list")
+		assert.Equal(t, "  2: Task 2\nX 1: Task 1\n", out, "expected recent tasks to stay")
+
+		// Backdate the completion to trigger the automatic archive
+		l := todo.List{}
+		if err := l.Get(fileName); err != nil {
+			t.Fatal(err)
+		}
+		i, err := l.Index(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Items[i].CompletedAt = l.Items[i].CompletedAt.AddDate(0, 0, -2)
+		if err := l.Save(fileName); err != nil {
+			t.Fatal(err)
+		}
+		out = run(t, "list")
+		assert.Equal(t, "  2: Task 2\nX 1: Task 1\n", out, "expected listing not to archive")
+		run(t, "add", "Task 3")
+		out = run(t, "list")
+		assert.Equal(t, "  2: Task 2\n  3: Task 3\n", out, "expected a change to archive")
+		out = run(t, "list", "-archived")
+		assert.Equal(t, "X 1: Task 1\n", out)
+	})
+	t.Run("UndoAfterArchive", func(t *testing.T) {
+		out := run(t, "undo")
+		assert.Contains(t, out, "3: Task 3", "expected the change of the command to be undone")
+		out = run(t, "list")
+		assert.Equal(t, "  2: Task 2\n", out)
+		out = run(t, "list", "-archived")
+		assert.Equal(t, "X 1: Task 1\n", out, "expected the archived task to stay archived")
+	})
+}
+
+func TestTodoCLIBoard(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), ".todo.json"))
+
+	run := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	must := func(t *testing.T, args ...string) string {
+		t.Helper()
+		out, err := run(t, args...)
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return out
+	}
+
+	must(t, "add", "Write report")
+	must(t, "add", "Call vendor")
+	t.Run("Status", func(t *testing.T) {
+		must(t, "status", "2", "blocked")
+		expected := "  1: Write report\n  2: Call vendor [blocked]\n"
+		out := must(t, "list")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+		_, err := run(t, "status", "2", "review")
+		assert.Error(t, err, "expected a status outside of the workflow to fail")
+	})
+	t.Run("Workflow", func(t *testing.T) {
+		must(t, "workflow", "todo,review,blocked,done")
+		must(t, "status", "1", "review")
+		expected := "TODO (0)  REVIEW (1)       BLOCKED (1)     DONE (0)\n" +
+			"          1: Write report  2: Call vendor\n"
+		out := must(t, "board")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+}
+
+func TestTodoCLIDependencies(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), ".todo.json"))
+
+	run := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	must := func(t *testing.T, args ...string) string {
+		t.Helper()
+		out, err := run(t, args...)
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return out
+	}
+
+	must(t, "add", "Write report")
+	must(t, "add", "Review report")
+	must(t, "add", "-deps", "1,2", "Send report")
+	must(t, "depend", "2", "1")
+	t.Run("Ready", func(t *testing.T) {
+		expected := "  1: Write report\n"
+		out := must(t, "list", "-ready")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+		out = must(t, "list")
+		assert.Contains(t, out, "3: Send report [blocked by 1,2]")
+	})
+	t.Run("Filtered", func(t *testing.T) {
+		out := must(t, "list", "-ready", "-query", "send")
+		assert.Empty(t, out, "expected blockers left out by the query to count")
+		out = must(t, "list", "-query", "send")
+		assert.Equal(t, "  3: Send report [blocked by 1,2]\n", out)
+	})
+	t.Run("Cycle", func(t *testing.T) {
+		out, err := run(t, "depend", "1", "3")
+		assert.Error(t, err, "expected a dependency cycle to fail")
+		assert.Contains(t, out, "cycle")
+	})
+	t.Run("Complete", func(t *testing.T) {
+		_, err := run(t, "complete", "2")
+		assert.Error(t, err, "expected completing a blocked item to fail")
+		must(t, "complete", "1")
+		expected := "  2: Review report\n"
+		out := must(t, "list", "-ready")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+	t.Run("Override", func(t *testing.T) {
+		must(t, "undepend", "3", "2")
+		expected := "  2: Review report\n  3: Send report\n"
+		out := must(t, "list", "-ready")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+		must(t, "depend", "3", "2")
+		must(t, "complete", "-force", "3")
+		out = must(t, "list")
+		assert.Contains(t, out, "X 3: Send report\n")
+	})
+}
+
+func TestTodoCLIReport(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), ".todo.json"))
+
+	must := func(t *testing.T, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return string(out)
+	}
+
+	must(t, "add", "Write report")
+	must(t, "add", "Archived task")
+	must(t, "add", "Open task")
+	must(t, "complete", "1")
+	must(t, "complete", "2")
+	must(t, "archive")
+	t.Run("Table", func(t *testing.T) {
+		out := must(t, "report", "-periods", "2", "day")
+		assert.Contains(t, out, "Completed: 2 (trend +2.0 per day)\n")
+		assert.Contains(t, out, "Open: 1\n")
+		assert.Contains(t, out, "OLDEST OPEN\n3: Open task")
+	})
+	t.Run("JSON", func(t *testing.T) {
+		var r todo.Report
+		out := must(t, "report", "-json", "week")
+		require.NoError(t, json.Unmarshal([]byte(out), &r), out)
+		assert.Len(t, r.Buckets, 8)
+		assert.Equal(t, 2, r.Completed, "expected archived tasks to be reported")
+		require.Len(t, r.Oldest, 1)
+		assert.Equal(t, "Open task", r.Oldest[0].Task)
+	})
+}
+
+func TestTodoCLIEdit(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), ".todo.json"), "VISUAL=")
+
+	run := func(t *testing.T, env []string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	must := func(t *testing.T, args ...string) string {
+		t.Helper()
+		out, err := run(t, env, args...)
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return out
+	}
+
+	for i := 1; i <= 6; i++ {
+		must(t, "add", fmt.Sprintf("Tsak %d", i))
+	}
+	t.Run("Edit", func(t *testing.T) {
+		must(t, "edit", "1", "Task", "1")
+		out, err := run(t, append(env, "EDITOR=sed -i s/Tsak/Task/"), "edit", "2")
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		_, err = run(t, append(env, "EDITOR="), "edit", "3")
+		assert.Error(t, err, "expected editing without an editor to fail")
+		out = must(t, "list")
+		assert.True(t, strings.HasPrefix(out, "  1: Task 1\n  2: Task 2\n  3: Tsak 3\n"), "got %q", out)
+	})
+	t.Run("Range", func(t *testing.T) {
+		must(t, "complete", "1-3,5")
+		_, err := run(t, env, "delete", "4,7")
+		assert.Error(t, err, "expected a range with a missing item to fail")
+		_, err = run(t, env, "reopen", "3-4")
+		assert.Error(t, err, "expected reopening an open item to fail")
+		must(t, "reopen", "2,3")
+		must(t, "delete", "5-6")
+		expected := "X 1: Task 1\n  2: Task 2\n  3: Tsak 3\n  4: Tsak 4\n"
+		out := must(t, "list")
+		assert.Equal(t, expected, out, "expected %q, got %q", expected, out)
+	})
+}
+
+func TestTodoCLICommands(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	tmp := t.TempDir()
+	config := filepath.Join(tmp, "config")
+	env := append(os.Environ(), "TODO_FILENAME=", "TODO_STORE=", "TODO_CONFIG="+config)
+
+	run := func(t *testing.T, args ...string) (string, int) {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		cmd.Dir = tmp
+		out, err := cmd.CombinedOutput()
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			t.Fatal(err)
+		}
+		return string(out), cmd.ProcessState.ExitCode()
+	}
+
+	t.Run("ExitCodes", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			args []string
+			code int
+		}{
+			{"NoCommand", nil, 2},
+			{"UnknownCommand", []string{"frobnicate"}, 2},
+			{"UnknownFlag", []string{"list", "-frobnicate"}, 2},
+			{"MissingArgument", []string{"complete"}, 2},
+			{"ExtraArgument", []string{"delete", "1", "2"}, 2},
+			{"InvalidID", []string{"restore", "one"}, 2},
+			{"Help", []string{"add", "-h"}, 0},
+			{"Failure", []string{"complete", "1"}, 1},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				out, code := run(t, tc.args...)
+				assert.Equal(t, tc.code, code, out)
+			})
+		}
+	})
+	t.Run("Config", func(t *testing.T) {
+		cfg := "# todo settings\nfile = tasks.json\nlist.verbose = true\n"
+		require.NoError(t, os.WriteFile(config, []byte(cfg), 0644))
+		_, code := run(t, "add", "Configured task")
+		require.Equal(t, 0, code)
+		_, err := os.Stat(filepath.Join(tmp, "tasks.json"))
+		assert.NoError(t, err, "expected the file set in the config")
+		out, _ := run(t, "list")
+		assert.Contains(t, out, "1: Configured task, Created at: ")
+		out, _ = run(t, "list", "-verbose=false")
+		assert.Equal(t, "  1: Configured task\n", out)
+
+		require.NoError(t, os.WriteFile(config, []byte("list.frobnicate = 1\n"), 0644))
+		out, code = run(t, "list")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, out, "config:1: unknown setting")
+	})
+	t.Run("Help", func(t *testing.T) {
+		require.NoError(t, os.Remove(config))
+		out, code := run(t, "help")
+		assert.Equal(t, 0, code)
+		assert.Contains(t, out, "  complete      Complete tasks")
+		out, _ = run(t, "help", "complete")
+		assert.Contains(t, out, "Usage: todo complete [flags] IDS")
+		assert.Contains(t, out, "-children")
+	})
+	t.Run("Completion", func(t *testing.T) {
+		for _, shell := range []string{"bash", "zsh", "fish"} {
+			out, code := run(t, "completion", shell)
+			assert.Equal(t, 0, code, out)
+			assert.Contains(t, out, "rename-list")
+			assert.Contains(t, out, "children")
+		}
+		if bash, err := exec.LookPath("bash"); err == nil {
+			out, _ := run(t, "completion", "bash")
+			check := exec.Command(bash, "-n")
+			check.Stdin = strings.NewReader(out)
+			msg, err := check.CombinedOutput()
+			assert.NoError(t, err, "invalid bash script: %s", msg)
+		}
+		_, code := run(t, "completion", "powershell")
+		assert.Equal(t, 2, code)
+	})
+}
+
+func TestTodoCLIDates(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), ".todo.json"))
+
+	run := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	must := func(t *testing.T, args ...string) string {
+		t.Helper()
+		out, err := run(t, args...)
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return out
+	}
+
+	t.Run("Due", func(t *testing.T) {
+		must(t, "add", "-due", "tomorrow 3pm", "call vendor")
+		must(t, "add", "-due", "in 2 days", "send invoice")
+		tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+		inTwoDays := time.Now().AddDate(0, 0, 2).Format(time.DateOnly)
+		out := must(t, "list", "-verbose")
+		assert.Contains(t, out, "call vendor, Due at: "+tomorrow+" 15:00:00")
+		assert.Contains(t, out, "send invoice, Due at: "+inTwoDays+" 23:59:59", "expected the end of the day")
+
+		_, err := run(t, "add", "-due", "someday", "read a book")
+		assert.Error(t, err, "expected an invalid date to fail")
+	})
+	t.Run("CreatedSince", func(t *testing.T) {
+		out := must(t, "list", "-created-since", "2 weeks ago")
+		assert.Contains(t, out, "call vendor")
+		out = must(t, "list", "-created-since", "tomorrow")
+		assert.Empty(t, out)
+	})
+}
+
+func TestTodoCLITemplates(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdPath := filepath.Join(dir, binName)
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), ".todo.json"))
+
+	run := func(t *testing.T, stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(cmdPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	must := func(t *testing.T, args ...string) string {
+		t.Helper()
+		out, err := run(t, "", args...)
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return out
+	}
+
+	t.Run("NewTemplate", func(t *testing.T) {
+		must(t, "new-template", "-task", "Release {version}", "release", "Tag v{version}", "Update the changelog", "Announce {version}")
+		out, err := run(t, "Milk\nBread\n\n", "new-template", "groceries")
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		_, err = run(t, "", "new-template", "release", "Tag")
+		assert.Error(t, err, "expected a duplicate template to fail")
+	})
+	t.Run("Templates", func(t *testing.T) {
+		exp := "groceries:\n  - Milk\n  - Bread\n" +
+			"release (version):\n  Release {version}\n    - Tag v{version}\n    - Update the changelog\n    - Announce {version}\n"
+		assert.Equal(t, exp, must(t, "templates"))
+		assert.True(t, strings.HasPrefix(must(t, "templates", "release"), "release (version):\n"))
+	})
+	t.Run("Instantiate", func(t *testing.T) {
+		_, err := run(t, "", "instantiate", "release")
+		assert.Error(t, err, "expected a missing parameter to fail")
+		must(t, "instantiate", "-tags", "ops", "release", "version=1.2")
+		must(t, "complete", "2")
+		must(t, "instantiate", "groceries")
+		exp := "  1: Release 1.2 #ops [1/3]\n" +
+			"X   2: Tag v1.2\n" +
+			"    3: Update the changelog\n" +
+			"    4: Announce 1.2\n" +
+			"  5: Milk\n" +
+			"  6: Bread\n"
+		assert.Equal(t, exp, must(t, "list"))
+	})
+	t.Run("DeleteTemplate", func(t *testing.T) {
+		must(t, "delete-template", "groceries")
+		_, err := run(t, "", "instantiate", "groceries")
+		assert.Error(t, err)
+	})
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/term"
+	"os"
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// getPassphrase returns the passphrase of the file name from the
+// environment variable env, prompting for it when the file is
+// encrypted and the variable is not set
+func getPassphrase(name, env string) (string, error) {
+	if p, ok := os.LookupEnv(env); ok {
+		return p, nil
+	}
+	encrypted, err := todo.IsEncrypted(name)
+	if err != nil || !encrypted {
+		return "", err
+	}
+	return prompt("Passphrase: ", env)
+}
+
+// getNewPassphrase returns the new passphrase from the environment
+// variable env or prompts for it twice. An empty passphrase removes
+// the encryption
+func getNewPassphrase(env string) (string, error) {
+	if p, ok := os.LookupEnv(env); ok {
+		return p, nil
+	}
+	p, err := prompt("New passphrase (empty to remove the encryption): ", env)
+	if err != nil {
+		return "", err
+	}
+	confirm, err := prompt("Confirm the new passphrase: ", env)
+	if err != nil {
+		return "", err
+	}
+	if p != confirm {
+		return "", errors.New("passphrases do not match")
+	}
+	return p, nil
+}
+
+// prompt reads a passphrase from the terminal without echoing it.
+// Without a terminal the passphrase must come from the variable env
+func prompt(message, env string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("no terminal to read the passphrase from, set the %s environment variable", env)
+	}
+	fmt.Fprint(os.Stderr, message)
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(p), err
+}
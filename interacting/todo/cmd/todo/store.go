@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"pragprog.com/rggo/interacting/todo"
+	"pragprog.com/rggo/interacting/todo/repository"
+)
+
+// getStore returns the storage backend selected with the -store flag,
+// journaling every update next to the list so it can be undone.
+// A non empty passphrase encrypts both the list and the journal
+func getStore(kind, name, passphrase string) (*todo.Journal, error) {
+	opt := todo.WithPassphrase(passphrase)
+	s, err := newStore(kind, name, passphrase, opt)
+	if err != nil {
+		return nil, err
+	}
+	return todo.NewJournal(s, name+".journal", opt), nil
+}
+
+// getArchive returns the store of the archived items,
+// kept next to the list with the same backend
+func getArchive(kind, name, passphrase string) (todo.Store, error) {
+	return newStore(kind, name+".archive", passphrase, todo.WithPassphrase(passphrase))
+}
+
+// newStore returns the storage backend of the given kind for name.
+// opt carries the passphrase for the backends supporting encryption
+func newStore(kind, name, passphrase string, opt todo.Option) (todo.Store, error) {
+	switch kind {
+	case "", "json":
+		return todo.NewFileStore(name, opt), nil
+	case "sqlite3":
+		if passphrase != "" {
+			return nil, fmt.Errorf("encryption is only supported by the json store")
+		}
+		repo, err := repository.NewSQLite3Repo(name)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	}
+	return nil, fmt.Errorf("unknown store %q: use json or sqlite3", kind)
+}
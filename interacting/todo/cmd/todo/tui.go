@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mum4k/termdash/keyboard"
+	"os"
+	"pragprog.com/rggo/interacting/todo"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// tuiMode tells what the keys do in the full screen interface
+type tuiMode int
+
+const (
+	modeBrowse tuiMode = iota
+	modeAdd
+	modeEdit
+	modeFilter
+)
+
+// tui is the state of the full screen interface: the tasks of the
+// active list matching the filter, the selected one and the line
+// being typed. It is only used by the goroutine running the interface
+type tui struct {
+	s      *todo.Journal
+	file   string
+	active string
+
+	l       todo.List
+	modTime time.Time
+	// ids and lines are the shown items, one per line
+	ids   []int
+	lines []string
+	// cursor is the index of the selected line and offset
+	// the index of the first line on the screen
+	cursor, offset int
+
+	mode    tuiMode
+	input   []rune
+	filter  string
+	message string
+	quit    bool
+}
+
+// newTUI returns the interface over the active list of the app
+func newTUI(a *app) *tui {
+	t := &tui{s: a.s, file: a.file, active: a.active}
+	t.reload()
+	return t
+}
+
+// reload loads the list from the store and remembers when its file
+// was last modified
+func (t *tui) reload() {
+	if info, err := os.Stat(t.file); err == nil {
+		t.modTime = info.ModTime()
+	}
+	l := todo.List{}
+	if err := t.s.Load(&l); err != nil {
+		t.message = err.Error()
+		return
+	}
+	t.l = l
+	t.refresh()
+}
+
+// changed reloads the list when its file was modified by another
+// process, reporting whether it did
+func (t *tui) changed() bool {
+	info, err := os.Stat(t.file)
+	if err != nil || info.ModTime().Equal(t.modTime) {
+		return false
+	}
+	t.reload()
+	t.message = "Reloaded: the tasks changed on disk"
+	return true
+}
+
+// refresh computes the shown lines from the list and the filter,
+// keeping the selected item when it is still shown
+func (t *tui) refresh() {
+	selected := t.selected()
+	items := t.l.In(t.active)
+	if t.filter != "" {
+		items = filterItems(items, t.filter)
+	}
+	items.Sort()
+	t.ids = items.Order()
+	t.lines = strings.Split(strings.TrimSuffix(items.String(), "\n"), "\n")
+	if len(t.ids) == 0 {
+		t.lines = nil
+	}
+	t.moveTo(selected)
+}
+
+// filterItems returns the items matching the filter expression. An
+// expression that does not parse yet, while it is being typed,
+// matches the tasks containing it
+func filterItems(items todo.List, filter string) todo.List {
+	if q, err := todo.ParseQuery(filter, time.Now()); err == nil {
+		return items.Select(q)
+	}
+	matched := todo.List{}
+	for _, value := range items.Items {
+		if strings.Contains(strings.ToLower(value.Task), strings.ToLower(filter)) {
+			matched.Items = append(matched.Items, value)
+		}
+	}
+	return matched
+}
+
+// selected returns the ID of the selected item, or 0 if none is shown
+func (t *tui) selected() int {
+	if t.cursor < 0 || t.cursor >= len(t.ids) {
+		return 0
+	}
+	return t.ids[t.cursor]
+}
+
+// moveTo selects the item with the given ID, or keeps the cursor
+// within the shown lines if it is not shown
+func (t *tui) moveTo(id int) {
+	for i, shown := range t.ids {
+		if shown == id {
+			t.cursor = i
+			return
+		}
+	}
+	t.move(0)
+}
+
+// move moves the cursor by n lines, stopping at the first and last one
+func (t *tui) move(n int) {
+	t.cursor = max(0, min(t.cursor+n, len(t.ids)-1))
+}
+
+// update applies fn to the list in the store, then reloads it and
+// selects the item with the ID returned by fn
+func (t *tui) update(fn func(l *todo.List) (int, error), done string) {
+	id := 0
+	err := t.s.Update(func(l *todo.List) error {
+		var err error
+		id, err = fn(l)
+		return err
+	})
+	t.reload()
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+	t.moveTo(id)
+	t.message = done
+}
+
+// key handles a key pressed in the current mode
+func (t *tui) key(k keyboard.Key) {
+	t.message = ""
+	if k == keyboard.KeyCtrlC {
+		t.quit = true
+		return
+	}
+	if t.mode != modeBrowse {
+		t.typeKey(k)
+		return
+	}
+
+	id := t.selected()
+	switch k {
+	case 'q':
+		t.quit = true
+	case 'j', keyboard.KeyArrowDown:
+		t.move(1)
+	case 'k', keyboard.KeyArrowUp:
+		t.move(-1)
+	case keyboard.KeyPgDn:
+		t.move(10)
+	case keyboard.KeyPgUp:
+		t.move(-10)
+	case 'g', keyboard.KeyHome:
+		t.move(-len(t.ids))
+	case 'G', keyboard.KeyEnd:
+		t.move(len(t.ids))
+	case 'a':
+		t.mode, t.input = modeAdd, nil
+	case '/':
+		t.mode, t.input = modeFilter, []rune(t.filter)
+	case keyboard.KeyEsc:
+		t.filter = ""
+		t.refresh()
+	case 'u':
+		t.undo(false)
+	case 'r':
+		t.undo(true)
+	}
+	if id == 0 {
+		return
+	}
+	switch k {
+	case 'e', keyboard.KeyEnter:
+		i, _ := t.l.Index(id)
+		t.mode, t.input = modeEdit, []rune(t.l.Items[i].Task)
+	case ' ', 'x':
+		t.toggle(id)
+	case 'd', keyboard.KeyDelete:
+		t.update(func(l *todo.List) (int, error) {
+			return id, l.Delete(id)
+		}, fmt.Sprintf("Deleted %d, press u to undo", id))
+	}
+}
+
+// typeKey edits the line typed to add or edit a task or to filter
+// the tasks, which are filtered as the filter is typed
+func (t *tui) typeKey(k keyboard.Key) {
+	switch {
+	case k == keyboard.KeyEsc:
+		if t.mode == modeFilter {
+			t.filter = ""
+			t.refresh()
+		}
+		t.mode = modeBrowse
+		return
+	case k == keyboard.KeyEnter:
+		t.submit()
+		return
+	case k == keyboard.KeyBackspace || k == keyboard.KeyBackspace2:
+		if len(t.input) > 0 {
+			t.input = t.input[:len(t.input)-1]
+		}
+	case k == keyboard.KeyCtrlU:
+		t.input = nil
+	case k >= 0 && unicode.IsPrint(rune(k)):
+		t.input = append(t.input, rune(k))
+	default:
+		return
+	}
+	if t.mode == modeFilter {
+		t.filter = strings.TrimSpace(string(t.input))
+		t.refresh()
+	}
+}
+
+// submit adds or edits the task typed, or keeps the filter
+func (t *tui) submit() {
+	mode, task := t.mode, strings.TrimSpace(string(t.input))
+	t.mode = modeBrowse
+	switch mode {
+	case modeAdd:
+		if task == "" {
+			return
+		}
+		t.update(func(l *todo.List) (int, error) {
+			id := l.Add(task)
+			return id, l.Move(id, t.active)
+		}, "Added "+task)
+	case modeEdit:
+		id := t.selected()
+		t.update(func(l *todo.List) (int, error) {
+			return id, l.Edit(id, task)
+		}, fmt.Sprintf("Edited %d", id))
+	}
+}
+
+// toggle completes the item, or reopens it if it is completed
+func (t *tui) toggle(id int) {
+	i, _ := t.l.Index(id)
+	if t.l.Items[i].Done() {
+		t.update(func(l *todo.List) (int, error) {
+			return id, l.Reopen(id)
+		}, fmt.Sprintf("Reopened %d", id))
+		return
+	}
+	t.update(func(l *todo.List) (int, error) {
+		return id, l.Complete(id)
+	}, fmt.Sprintf("Completed %d", id))
+}
+
+// undo undoes the last change, or redoes the last undone one
+func (t *tui) undo(redo bool) {
+	op, e, err := "Undid", todo.Entry{}, error(nil)
+	if redo {
+		op = "Redid"
+		e, err = t.s.Redo()
+	} else {
+		e, err = t.s.Undo()
+	}
+	t.reload()
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+	t.message = fmt.Sprintf("%s: %s", op, e)
+}
+
+// view returns the lines fitting in height, scrolled to show the
+// cursor, along with the index of the selected one among them
+func (t *tui) view(height int) ([]string, int) {
+	if height < 1 || len(t.lines) == 0 {
+		return nil, -1
+	}
+	switch {
+	case t.cursor < t.offset:
+		t.offset = t.cursor
+	case t.cursor >= t.offset+height:
+		t.offset = t.cursor - height + 1
+	}
+	t.offset = max(0, min(t.offset, len(t.lines)-height))
+	end := min(t.offset+height, len(t.lines))
+	return t.lines[t.offset:end], t.cursor - t.offset
+}
+
+// prompt returns the line at the bottom of the interface: the line
+// being typed, or the last message along with the keys
+func (t *tui) prompt() string {
+	switch t.mode {
+	case modeAdd:
+		return "Add: " + string(t.input) + "_"
+	case modeEdit:
+		return fmt.Sprintf("Edit %d: %s_", t.selected(), string(t.input))
+	case modeFilter:
+		return "Filter: " + string(t.input) + "_"
+	}
+	keys := "a add  e edit  space complete  d delete  / filter  u undo  r redo  q quit"
+	if t.message != "" {
+		return t.message + "  |  " + keys
+	}
+	return keys
+}
+
+// title returns the title of the interface with the list and filter
+func (t *tui) title() string {
+	title := fmt.Sprintf("todo: %s (%d)", t.active, len(t.ids))
+	if t.filter != "" {
+		title += " filter: " + t.filter
+	}
+	return title
+}
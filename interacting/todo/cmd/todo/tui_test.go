@@ -0,0 +1,141 @@
+package main
+
+import (
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+	"time"
+)
+
+// newTestTUI returns the interface over a list with the given tasks
+func newTestTUI(t *testing.T, tasks ...string) *tui {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), ".todo.json")
+	s, err := getStore("", file, "")
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	require.NoError(t, s.Update(func(l *todo.List) error {
+		for _, task := range tasks {
+			l.Add(task)
+		}
+		return nil
+	}))
+	return newTUI(&app{s: s, file: file, active: todo.DefaultList})
+}
+
+// typeKeys sends the keys of s to the interface followed by the given keys
+func typeKeys(ui *tui, s string, keys ...keyboard.Key) {
+	for _, r := range s {
+		ui.key(keyboard.Key(r))
+	}
+	for _, k := range keys {
+		ui.key(k)
+	}
+}
+
+func TestTUI(t *testing.T) {
+	t.Run("Navigate", func(t *testing.T) {
+		ui := newTestTUI(t, "One", "Two", "Three")
+		assert.Equal(t, 1, ui.selected())
+		typeKeys(ui, "jj", keyboard.KeyArrowDown)
+		assert.Equal(t, 3, ui.selected(), "expected the cursor to stop at the last item")
+		typeKeys(ui, "k")
+		assert.Equal(t, 2, ui.selected())
+		typeKeys(ui, "g")
+		assert.Equal(t, 1, ui.selected())
+
+		lines, cursor := ui.view(2)
+		assert.Equal(t, []string{"  1: One", "  2: Two"}, lines)
+		assert.Equal(t, 0, cursor)
+		typeKeys(ui, "G")
+		lines, cursor = ui.view(2)
+		assert.Equal(t, []string{"  2: Two", "  3: Three"}, lines, "expected the view to scroll to the cursor")
+		assert.Equal(t, 1, cursor)
+	})
+
+	t.Run("AddEditComplete", func(t *testing.T) {
+		ui := newTestTUI(t, "One")
+		typeKeys(ui, "aTwoo", keyboard.KeyBackspace2, keyboard.KeyEnter)
+		assert.Equal(t, 2, ui.selected(), "expected the new item to be selected")
+		assert.Equal(t, "Added Two", ui.message)
+
+		typeKeys(ui, "e", keyboard.KeyCtrlU)
+		assert.Equal(t, "Edit 2: _", ui.prompt())
+		typeKeys(ui, "Second", keyboard.KeyEnter)
+		typeKeys(ui, " ")
+		assert.Equal(t, []string{"  1: One", "X 2: Second"}, ui.lines)
+
+		typeKeys(ui, "x")
+		assert.Equal(t, "Reopened 2", ui.message)
+		assert.Equal(t, "  2: Second", ui.lines[1])
+
+		typeKeys(ui, "aNot added", keyboard.KeyEsc)
+		assert.Len(t, ui.lines, 2, "expected Esc to cancel the new task")
+		typeKeys(ui, "q")
+		assert.True(t, ui.quit)
+	})
+
+	t.Run("DeleteUndo", func(t *testing.T) {
+		ui := newTestTUI(t, "One", "Two")
+		typeKeys(ui, "jd")
+		assert.Equal(t, []string{"  1: One"}, ui.lines)
+		assert.Equal(t, 1, ui.selected())
+		typeKeys(ui, "u")
+		assert.Equal(t, []string{"  1: One", "  2: Two"}, ui.lines)
+		typeKeys(ui, "r")
+		assert.Equal(t, []string{"  1: One"}, ui.lines)
+	})
+
+	t.Run("Blocked", func(t *testing.T) {
+		ui := newTestTUI(t, "One", "Two")
+		require.NoError(t, ui.s.Update(func(l *todo.List) error {
+			return l.AddDependency(2, 1)
+		}))
+		ui.reload()
+		typeKeys(ui, "j ")
+		assert.Contains(t, ui.message, todo.ErrBlocked.Error())
+		assert.Equal(t, "  2: Two [blocked by 1]", ui.lines[1])
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		ui := newTestTUI(t, "Deploy app", "Write docs", "Deploy docs")
+		typeKeys(ui, "G/dep")
+		assert.Equal(t, []int{1, 3}, ui.ids, "expected the tasks to be filtered as the filter is typed")
+		assert.Equal(t, 3, ui.selected(), "expected the selected item to stay selected")
+		typeKeys(ui, "loy and docs", keyboard.KeyEnter)
+		assert.Equal(t, []int{3}, ui.ids)
+		assert.Equal(t, modeBrowse, ui.mode)
+		assert.Equal(t, "todo: default (1) filter: deploy and docs", ui.title())
+
+		typeKeys(ui, "/", keyboard.KeyCtrlU)
+		typeKeys(ui, "tag:")
+		assert.Empty(t, ui.ids, "expected an incomplete filter to match the task text")
+		typeKeys(ui, "", keyboard.KeyEsc)
+		assert.Equal(t, []int{1, 2, 3}, ui.ids, "expected Esc to clear the filter")
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		ui := newTestTUI(t, "One")
+		assert.False(t, ui.changed())
+
+		// Change the tasks from another process
+		s, err := getStore("", ui.file, "")
+		require.NoError(t, err)
+		defer s.Close()
+		require.NoError(t, s.Update(func(l *todo.List) error {
+			l.Add("Two")
+			return nil
+		}))
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(ui.file, later, later))
+
+		assert.True(t, ui.changed())
+		assert.Equal(t, []int{1, 2}, ui.ids)
+		assert.Contains(t, ui.prompt(), "Reloaded")
+		assert.False(t, ui.changed(), "expected no reload without a change")
+	})
+}
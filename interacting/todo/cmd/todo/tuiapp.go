@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/mum4k/termdash"
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/terminal/tcell"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
+	"image"
+	"time"
+)
+
+// tuiID identifies the container of the interface to update its title
+const tuiID = "todo"
+
+// reloadInterval is how often the file of the tasks is checked
+// for changes made by other processes
+const reloadInterval = time.Second
+
+// runTUI runs the full screen interface over the active list of the
+// app until the user quits. The keys and the checks for changes on
+// disk are handled by this goroutine only, so the state needs no lock
+func runTUI(a *app) error {
+	t := newTUI(a)
+	term, err := tcell.New()
+	if err != nil {
+		return err
+	}
+	defer term.Close()
+	txt, err := text.New(text.DisableScrolling())
+	if err != nil {
+		return err
+	}
+	c, err := container.New(term,
+		container.ID(tuiID),
+		container.Border(linestyle.Light),
+		container.BorderTitle(t.title()),
+		container.PlaceWidget(txt),
+	)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	keys := make(chan keyboard.Key)
+	subscriber := func(k *terminalapi.Keyboard) {
+		select {
+		case keys <- k.Key:
+		case <-ctx.Done():
+		}
+	}
+	controller, err := termdash.NewController(term, c, termdash.KeyboardSubscriber(subscriber))
+	if err != nil {
+		cancel()
+		return err
+	}
+	defer controller.Close()
+	defer cancel()
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	var size image.Point
+	for !t.quit {
+		if !size.Eq(term.Size()) {
+			size = term.Size()
+			if err := term.Clear(); err != nil {
+				return err
+			}
+		}
+		if err := render(t, c, txt, size); err != nil {
+			return err
+		}
+		if err := controller.Redraw(); err != nil {
+			return err
+		}
+	wait:
+		for {
+			select {
+			case k := <-keys:
+				t.key(k)
+				break wait
+			case <-ticker.C:
+				if t.changed() || !size.Eq(term.Size()) {
+					break wait
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// render writes the shown tasks with the selected one highlighted
+// and the prompt on the last line of the terminal of the given size
+func render(t *tui, c *container.Container, txt *text.Text, size image.Point) error {
+	// Leave room for the border and the prompt
+	width, height := size.X-2, size.Y-2
+	lines, cursor := t.view(height - 2)
+	txt.Reset()
+	if len(lines) == 0 {
+		empty := "No tasks, press a to add one"
+		if t.filter != "" {
+			empty = "No tasks match the filter, press Esc to clear it"
+		}
+		lines = []string{empty}
+	}
+	for i, line := range lines {
+		var opts []text.WriteOption
+		if i == cursor {
+			opts = append(opts, text.WriteCellOpts(cell.Inverse()))
+		}
+		if err := txt.Write(fmt.Sprintf("%-*s\n", width, line), opts...); err != nil {
+			return err
+		}
+	}
+	for i := len(lines); i < height-1; i++ {
+		if err := txt.Write("\n"); err != nil {
+			return err
+		}
+	}
+	if err := txt.Write(t.prompt(), text.WriteCellOpts(cell.Bold())); err != nil {
+		return err
+	}
+	return c.Update(tuiID, container.BorderTitle(t.title()))
+}
@@ -0,0 +1,246 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidDate = errors.New("invalid date")
+)
+
+var timeOfDayRe = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+
+// ParseDate parses a date expression relative to now, in the location
+// of now. timed reports whether the expression has a time of day;
+// otherwise the date is at the start of the day. Expressions are an
+// optional date followed by an optional time of day:
+//
+//	now, today, tomorrow, yesterday
+//	2024-01-31, 2024-01-31T15:04 or RFC 3339
+//	friday, this fri    today or the next friday
+//	next friday         the next friday after today
+//	last friday         the last friday before today
+//	next week           also last, with day, month and year
+//	this week           the monday of this week, also the first
+//	                    day of this month and this year
+//	in 3 days           also with a or an, and with minutes,
+//	2 weeks ago         hours, days, weeks, months and years
+//	+3d, -2w            days or weeks from today
+//	3pm, 3:30pm, 15:00, noon, midnight, optionally after at
+//
+// A time of day alone is today at that time, so "next friday 3pm"
+// and "tomorrow at 9:30" are both timed
+func ParseDate(s string, now time.Time) (t time.Time, timed bool, err error) {
+	if t, err := time.ParseInLocation(time.RFC3339, strings.TrimSpace(s), now.Location()); err == nil {
+		return t, true, nil
+	}
+	words := strings.Fields(strings.ToLower(s))
+	if len(words) == 0 {
+		return time.Time{}, false, fmt.Errorf("%w: empty date", ErrInvalidDate)
+	}
+	t, timed, rest, ok := leadingDate(words, now)
+	if !ok {
+		t, rest = startOfDay(now), words
+	}
+	if len(rest) > 0 && rest[0] == "at" {
+		rest = rest[1:]
+	}
+	if len(rest) == 0 {
+		if !ok {
+			return time.Time{}, false, fmt.Errorf("%w: %q", ErrInvalidDate, s)
+		}
+		return t, timed, nil
+	}
+	h, m, err := parseTimeOfDay(strings.Join(rest, ""))
+	if err != nil || timed {
+		return time.Time{}, false, fmt.Errorf("%w: %q", ErrInvalidDate, s)
+	}
+	y, mon, d := t.Date()
+	return time.Date(y, mon, d, h, m, 0, 0, t.Location()), true, nil
+}
+
+// leadingDate parses the date at the start of words. It returns the date,
+// whether it has a time of day and the words left after it, or false
+// if words does not start with a date
+func leadingDate(words []string, now time.Time) (time.Time, bool, []string, bool) {
+	today := startOfDay(now)
+	w := words[0]
+	switch w {
+	case "now":
+		return now, true, words[1:], true
+	case "today":
+		return today, false, words[1:], true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), false, words[1:], true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), false, words[1:], true
+	}
+	for _, layout := range []string{time.DateOnly, "2006-01-02T15:04", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, strings.ToUpper(w), now.Location()); err == nil {
+			return t, layout != time.DateOnly, words[1:], true
+		}
+	}
+	if d, ok := parseWeekday(w); ok {
+		return nextWeekday(today, d, 0), false, words[1:], true
+	}
+	if n := len(w); n > 2 && (w[0] == '-' || w[0] == '+') {
+		if offset, err := strconv.Atoi(w[:n-1]); err == nil {
+			switch w[n-1] {
+			case 'd':
+				return today.AddDate(0, 0, offset), false, words[1:], true
+			case 'w':
+				return today.AddDate(0, 0, 7*offset), false, words[1:], true
+			}
+		}
+	}
+	if len(words) < 2 {
+		return time.Time{}, false, nil, false
+	}
+
+	// this, next or last followed by a weekday or a unit
+	sign := 0
+	switch w {
+	case "next":
+		sign = 1
+	case "last":
+		sign = -1
+	}
+	if sign != 0 || w == "this" {
+		if d, ok := parseWeekday(words[1]); ok {
+			return nextWeekday(today, d, sign), false, words[2:], true
+		}
+		if t, timed, ok := addUnit(now, sign, words[1]); ok && sign != 0 {
+			return t, timed, words[2:], true
+		}
+		if t, ok := startOfUnit(today, words[1]); ok && sign == 0 {
+			return t, false, words[2:], true
+		}
+		return time.Time{}, false, nil, false
+	}
+
+	// in N units, or N units ago
+	if w == "in" && len(words) >= 3 {
+		if n, ok := parseCount(words[1]); ok {
+			if t, timed, ok := addUnit(now, n, words[2]); ok {
+				return t, timed, words[3:], true
+			}
+		}
+	}
+	if len(words) >= 3 && words[2] == "ago" {
+		if n, ok := parseCount(w); ok {
+			if t, timed, ok := addUnit(now, -n, words[1]); ok {
+				return t, timed, words[3:], true
+			}
+		}
+	}
+	return time.Time{}, false, nil, false
+}
+
+// parseCount parses a positive number of units, where a or an is one
+func parseCount(w string) (int, bool) {
+	if w == "a" || w == "an" {
+		return 1, true
+	}
+	n, err := strconv.Atoi(w)
+	return n, err == nil && n > 0
+}
+
+// addUnit adds n units to now. Minutes and hours keep the time of day,
+// while larger units are added to the start of the day
+func addUnit(now time.Time, n int, unit string) (time.Time, bool, bool) {
+	switch strings.TrimSuffix(unit, "s") {
+	case "minute", "min":
+		return now.Add(time.Duration(n) * time.Minute), true, true
+	case "hour":
+		return now.Add(time.Duration(n) * time.Hour), true, true
+	case "day":
+		return startOfDay(now).AddDate(0, 0, n), false, true
+	case "week":
+		return startOfDay(now).AddDate(0, 0, 7*n), false, true
+	case "month":
+		return startOfDay(now).AddDate(0, n, 0), false, true
+	case "year":
+		return startOfDay(now).AddDate(n, 0, 0), false, true
+	}
+	return time.Time{}, false, false
+}
+
+// startOfUnit returns the first day of the week, starting on monday,
+// month or year holding today
+func startOfUnit(today time.Time, unit string) (time.Time, bool) {
+	switch unit {
+	case "week":
+		return today.AddDate(0, 0, -(int(today.Weekday())+6)%7), true
+	case "month":
+		return today.AddDate(0, 0, 1-today.Day()), true
+	case "year":
+		return today.AddDate(0, 0, 1-today.YearDay()), true
+	}
+	return time.Time{}, false
+}
+
+// parseWeekday parses the full or three letter name of a weekday
+func parseWeekday(w string) (time.Weekday, bool) {
+	for i, short := range weekdays {
+		if w == short || w == strings.ToLower(time.Weekday(i).String()) {
+			return time.Weekday(i), true
+		}
+	}
+	return 0, false
+}
+
+// nextWeekday returns the day d from today: today or the next one for
+// a zero sign, the next one after today for a positive sign and the
+// last one before today for a negative sign
+func nextWeekday(today time.Time, d time.Weekday, sign int) time.Time {
+	days := (int(d) - int(today.Weekday()) + 7) % 7
+	switch {
+	case sign > 0 && days == 0:
+		days = 7
+	case sign < 0:
+		days -= 7
+	}
+	return today.AddDate(0, 0, days)
+}
+
+// parseTimeOfDay parses a time of day such as 3pm, 3:30pm or 15:00
+func parseTimeOfDay(s string) (int, int, error) {
+	switch s {
+	case "noon":
+		return 12, 0, nil
+	case "midnight":
+		return 0, 0, nil
+	}
+	m := timeOfDayRe.FindStringSubmatch(s)
+	if m == nil || (m[2] == "" && m[3] == "") {
+		return 0, 0, fmt.Errorf("%w: invalid time of day %q", ErrInvalidDate, s)
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	switch {
+	case m[3] != "" && (hour < 1 || hour > 12):
+		return 0, 0, fmt.Errorf("%w: invalid hour %d%s", ErrInvalidDate, hour, m[3])
+	case m[3] == "am" && hour == 12:
+		hour = 0
+	case m[3] == "pm" && hour < 12:
+		hour += 12
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, fmt.Errorf("%w: invalid time of day %q", ErrInvalidDate, s)
+	}
+	return hour, minute, nil
+}
+
+// startOfDay returns midnight of the day of t in its location
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
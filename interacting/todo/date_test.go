@@ -0,0 +1,82 @@
+package todo_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 10, 14, 10, 30, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+	at := func(d, h, m int) time.Time { return time.Date(2026, 10, d, h, m, 0, 0, time.UTC) }
+
+	testCases := []struct {
+		expr  string
+		exp   time.Time
+		timed bool
+	}{
+		{"now", now, true},
+		{"today", day(14), false},
+		{"Tomorrow", day(15), false},
+		{"yesterday", day(13), false},
+		{"2026-11-02", time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), false},
+		{"2026-11-02T08:15", time.Date(2026, 11, 2, 8, 15, 0, 0, time.UTC), true},
+		{"2026-11-02T08:15:00Z", time.Date(2026, 11, 2, 8, 15, 0, 0, time.UTC), true},
+		{"2026-11-02 8:15am", time.Date(2026, 11, 2, 8, 15, 0, 0, time.UTC), true},
+		{"friday", day(16), false},
+		{"wed", day(14), false},
+		{"this wednesday", day(14), false},
+		{"next wednesday", day(21), false},
+		{"next friday 3pm", at(16, 15, 0), true},
+		{"last monday", day(12), false},
+		{"last wednesday", day(7), false},
+		{"next week", day(21), false},
+		{"this week", day(12), false},
+		{"this month", day(1), false},
+		{"This Year", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"last month", time.Date(2026, 9, 14, 0, 0, 0, 0, time.UTC), false},
+		{"next year", time.Date(2027, 10, 14, 0, 0, 0, 0, time.UTC), false},
+		{"in 3 days", day(17), false},
+		{"in a week", day(21), false},
+		{"2 weeks ago", time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC), false},
+		{"in 2 hours", at(14, 12, 30), true},
+		{"an hour ago", at(14, 9, 30), true},
+		{"in 45 minutes", at(14, 11, 15), true},
+		{"+3d", day(17), false},
+		{"-1w", day(7), false},
+		{"3pm", at(14, 15, 0), true},
+		{"3 PM", at(14, 15, 0), true},
+		{"at 9:30", at(14, 9, 30), true},
+		{"tomorrow at noon", at(15, 12, 0), true},
+		{"tomorrow midnight", day(15), true},
+		{"12am", day(14), true},
+		{"12:05pm", at(14, 12, 5), true},
+		{"23:59", at(14, 23, 59), true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			d, timed, err := todo.ParseDate(tc.expr, now)
+			require.NoError(t, err)
+			assert.True(t, tc.exp.Equal(d), "expected %s, got %s", tc.exp, d)
+			assert.Equal(t, tc.timed, timed)
+		})
+	}
+
+	for _, expr := range []string{"", "someday", "3", "next", "this hour", "this weeks", "in 2 fortnights", "13pm", "24:00", "9:75",
+		"in 2 hours 3pm", "2026-02-30", "friday 3pm tomorrow", "0 days ago"} {
+		_, _, err := todo.ParseDate(expr, now)
+		assert.ErrorIs(t, err, todo.ErrInvalidDate, "expected %q to be rejected", expr)
+	}
+}
+
+func TestParseDate_Location(t *testing.T) {
+	loc := time.FixedZone("UTC-5", -5*60*60)
+	now := time.Date(2026, 10, 14, 22, 0, 0, 0, loc)
+	d, _, err := todo.ParseDate("tomorrow 9am", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 15, 9, 0, 0, 0, loc), d)
+}
@@ -0,0 +1,232 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBlocked         = errors.New("item has open dependencies")
+	ErrDependencyCycle = errors.New("dependency cycle")
+	ErrInvalidDep      = errors.New("invalid dependency")
+)
+
+// AddDependency makes the item with the given ID depend on the item
+// with the ID dep, so it cannot be completed before dep.
+// Dependencies that would make an item depend on itself are refused
+func (l *List) AddDependency(id, dep int) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	if _, err := l.Index(dep); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDep, err)
+	}
+	if dep == id || slices.Contains(l.dependencies(dep), id) {
+		return fmt.Errorf("%w: %d already depends on %d", ErrDependencyCycle, dep, id)
+	}
+	if !slices.Contains(l.Items[i].DependsOn, dep) {
+		l.Items[i].DependsOn = append(l.Items[i].DependsOn, dep)
+		slices.Sort(l.Items[i].DependsOn)
+	}
+	return nil
+}
+
+// RemoveDependency removes the dependency of the item with
+// the given ID on the item with the ID dep
+func (l *List) RemoveDependency(id, dep int) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	deps := slices.DeleteFunc(l.Items[i].DependsOn, func(d int) bool { return d == dep })
+	if len(deps) == 0 {
+		deps = nil
+	}
+	l.Items[i].DependsOn = deps
+	return nil
+}
+
+// Blockers returns the IDs of the open items the item with
+// the given ID depends on. For the items returned by In, Select
+// or Ready, they are looked up in the whole list
+func (l *List) Blockers(id int) []int {
+	i, err := l.Index(id)
+	if err != nil {
+		return nil
+	}
+	var open []int
+	all := l.whole()
+	for _, dep := range l.Items[i].DependsOn {
+		if d, err := all.Index(dep); err == nil && !all.Items[d].Done() {
+			open = append(open, dep)
+		}
+	}
+	return open
+}
+
+// Ready returns the open items that can be worked on, which are the
+// ones without open dependencies and not in StatusBlocked
+func (l *List) Ready() List {
+	ready := List{from: l.whole()}
+	for _, value := range l.Items {
+		if value.Done() || value.Status == StatusBlocked || len(l.Blockers(value.ID)) > 0 {
+			continue
+		}
+		ready.Items = append(ready.Items, value)
+	}
+	return ready
+}
+
+// ForceComplete completes the item with the given ID
+// even if its dependencies are still open
+func (l *List) ForceComplete(id int) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	l.setStatus(i, StatusDone, time.Now())
+	return nil
+}
+
+// checkBlockers returns ErrBlocked if the item with the
+// given ID has open dependencies
+func (l *List) checkBlockers(id int) error {
+	blockers := l.Blockers(id)
+	if len(blockers) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d is waiting for %s", ErrBlocked, id, formatIDs(blockers))
+}
+
+// dependencies returns the IDs of all items the item
+// with the given ID depends on, directly or not
+func (l *List) dependencies(id int) []int {
+	var ids []int
+	queue := []int{id}
+	for len(queue) > 0 {
+		i, err := l.Index(queue[0])
+		queue = queue[1:]
+		if err != nil {
+			continue
+		}
+		for _, dep := range l.Items[i].DependsOn {
+			if !slices.Contains(ids, dep) {
+				ids = append(ids, dep)
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return ids
+}
+
+// dropDependencies removes the dependencies on the given IDs
+func (l *List) dropDependencies(ids []int) {
+	ls := l.Items
+	for i := range ls {
+		if len(ls[i].DependsOn) == 0 {
+			continue
+		}
+		deps := slices.DeleteFunc(ls[i].DependsOn, func(d int) bool { return slices.Contains(ids, d) })
+		if len(deps) == 0 {
+			deps = nil
+		}
+		ls[i].DependsOn = deps
+	}
+}
+
+// pruneDependencies drops the dependencies on items missing from the
+// list and the ones closing a cycle, which AddDependency refuses but
+// merged or imported items can hold. Dependencies are checked in list
+// order, so the last one closing a cycle is dropped.
+// It returns a Conflict describing each dropped dependency
+func (l *List) pruneDependencies() []Conflict {
+	ids := map[int]bool{}
+	for _, value := range l.Items {
+		ids[value.ID] = true
+	}
+	kept := map[int][]int{}
+	// reaches reports whether to is from or one of the items
+	// from depends on through the dependencies kept so far
+	reaches := func(from, to int) bool {
+		seen := map[int]bool{}
+		queue := []int{from}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			if id == to {
+				return true
+			}
+			if !seen[id] {
+				seen[id] = true
+				queue = append(queue, kept[id]...)
+			}
+		}
+		return false
+	}
+
+	var dropped []Conflict
+	for i := range l.Items {
+		value := &l.Items[i]
+		var deps []int
+		for _, dep := range value.DependsOn {
+			reason := ""
+			switch {
+			case !ids[dep]:
+				reason = fmt.Sprintf("dropped the dependency on missing item %d", dep)
+			case reaches(dep, value.ID):
+				reason = fmt.Sprintf("dropped the dependency on %d closing a cycle", dep)
+			}
+			if reason != "" {
+				dropped = append(dropped, Conflict{value.ID, value.Task, reason})
+				continue
+			}
+			deps = append(deps, dep)
+			kept[value.ID] = append(kept[value.ID], dep)
+		}
+		value.DependsOn = deps
+	}
+	return dropped
+}
+
+// renumberIDs replaces the IDs found in renumbered by their new ID.
+// It returns nil when no IDs are left
+func renumberIDs(ids []int, renumbered map[int]int) []int {
+	if len(ids) == 0 {
+		return nil
+	}
+	for i, id := range ids {
+		if n, ok := renumbered[id]; ok {
+			ids[i] = n
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// formatIDs formats IDs as a comma separated list
+func formatIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = fmt.Sprint(id)
+	}
+	return strings.Join(s, ",")
+}
+
+// parseIDs parses a comma separated list of IDs
+func parseIDs(s string) ([]int, error) {
+	var ids []int
+	for _, f := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
@@ -0,0 +1,106 @@
+package todo_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+	"time"
+)
+
+func TestList_AddDependency(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	l.Add("Task 3")
+
+	require.NoError(t, l.AddDependency(2, 1))
+	require.NoError(t, l.AddDependency(3, 2))
+	require.NoError(t, l.AddDependency(3, 2))
+	assert.Equal(t, []int{2}, l.Items[2].DependsOn, "expected dependencies to be unique")
+
+	assert.ErrorIs(t, l.AddDependency(1, 3), todo.ErrDependencyCycle)
+	assert.ErrorIs(t, l.AddDependency(1, 1), todo.ErrDependencyCycle)
+	assert.ErrorIs(t, l.AddDependency(1, 4), todo.ErrInvalidDep)
+	assert.ErrorIs(t, l.AddDependency(4, 1), todo.ErrNotExists)
+
+	require.NoError(t, l.RemoveDependency(3, 2))
+	assert.Empty(t, l.Items[2].DependsOn)
+	require.NoError(t, l.AddDependency(1, 3), "expected no cycle once the dependency is removed")
+}
+
+func TestList_CompleteBlocked(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	require.NoError(t, l.AddDependency(2, 1))
+
+	assert.Equal(t, []int{1}, l.Blockers(2))
+	assert.ErrorIs(t, l.Complete(2), todo.ErrBlocked)
+	assert.ErrorIs(t, l.SetStatus(2, todo.StatusDone, todo.DefaultWorkflow), todo.ErrBlocked)
+	assert.Equal(t, "  1: Task 1\n  2: Task 2 [blocked by 1]\n", l.String())
+	assert.False(t, l.Items[1].Done())
+
+	require.NoError(t, l.ForceComplete(2))
+	assert.True(t, l.Items[1].Done())
+
+	require.NoError(t, l.Complete(1))
+	assert.Empty(t, l.Blockers(2))
+}
+
+func TestList_CompleteTreeBlocked(t *testing.T) {
+	l := todo.List{}
+	l.Add("Parent")
+	sub, err := l.AddSub(1, "Sub")
+	require.NoError(t, err)
+	require.NoError(t, l.AddDependency(1, sub))
+	other := l.Add("Other")
+
+	require.NoError(t, l.CompleteTree(1), "expected dependencies within the tree to be ignored")
+
+	l.Add("Parent 2")
+	sub, err = l.AddSub(4, "Sub 2")
+	require.NoError(t, err)
+	require.NoError(t, l.AddDependency(sub, other))
+	assert.ErrorIs(t, l.CompleteTree(4), todo.ErrBlocked)
+	_, err = l.Index(4)
+	require.NoError(t, err)
+	assert.False(t, l.Items[3].Done())
+}
+
+func TestList_Ready(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	l.Add("Task 3")
+	l.Add("Task 4")
+	require.NoError(t, l.AddDependency(2, 1))
+	require.NoError(t, l.SetStatus(3, todo.StatusBlocked, todo.DefaultWorkflow))
+	require.NoError(t, l.Complete(4))
+
+	ready := l.Ready()
+	require.Len(t, ready.Items, 1)
+	assert.Equal(t, 1, ready.Items[0].ID)
+
+	require.NoError(t, l.Delete(1))
+	assert.Empty(t, l.Items[0].DependsOn, "expected dependencies on deleted items to be dropped")
+	ready = l.Ready()
+	require.Len(t, ready.Items, 1)
+	assert.Equal(t, 2, ready.Items[0].ID)
+}
+
+func TestList_BlockersFiltered(t *testing.T) {
+	l := todo.List{}
+	l.Add("Order parts")
+	l.Add("Deploy release")
+	require.NoError(t, l.Move(1, "shop"))
+	require.NoError(t, l.AddDependency(2, 1))
+	q, err := todo.ParseQuery("deploy", time.Now())
+	require.NoError(t, err)
+
+	for _, items := range []todo.List{l.In(todo.DefaultList), l.Select(q)} {
+		assert.Empty(t, items.Ready().Items, "expected a blocker left out to count")
+		assert.Equal(t, []int{1}, items.Blockers(2))
+		assert.Equal(t, "  2: Deploy release [blocked by 1]\n", items.String())
+	}
+}
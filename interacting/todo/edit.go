@@ -0,0 +1,205 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrEmptyTask    = errors.New("task cannot be blank")
+	ErrNotCompleted = errors.New("item is not completed")
+	ErrInvalidRange = errors.New("invalid range")
+	ErrInvalidItem  = errors.New("invalid item")
+)
+
+// Edit replaces the task of the item with the given ID,
+// keeping the rest of the item as it is
+func (l *List) Edit(id int, task string) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	task = strings.TrimSpace(task)
+	if task == "" || strings.Contains(task, "\n") {
+		return fmt.Errorf("%w: it must be a single non-blank line", ErrEmptyTask)
+	}
+	l.Items[i].Task = task
+	return nil
+}
+
+// Reopen moves the completed item with the given ID back to
+// StatusTodo and clears its completion time
+func (l *List) Reopen(id int) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	if !l.Items[i].Done() {
+		return fmt.Errorf("%w: %d", ErrNotCompleted, id)
+	}
+	l.setStatus(i, StatusTodo, time.Now())
+	return nil
+}
+
+// Replace replaces the item with the given ID with the item encoded
+// as JSON in data, which holds every field to keep: missing fields are
+// cleared. The ID, creation and completion times and transitions are
+// kept, and the status changes like with SetStatus, to a status of the
+// workflow w. The new fields are checked like the methods setting them,
+// and the list is left untouched if any of them is invalid
+func (l *List) Replace(id int, data []byte, w Workflow) error {
+	if _, err := l.Index(id); err != nil {
+		return err
+	}
+	var with item
+	if err := json.Unmarshal(data, &with); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidItem, err)
+	}
+	if with.ID != 0 && with.ID != id {
+		return fmt.Errorf("%w: the ID of %d cannot change to %d", ErrInvalidItem, id, with.ID)
+	}
+
+	c := l.Clone()
+	if err := c.Edit(id, with.Task); err != nil {
+		return err
+	}
+	if err := c.ClearPriority(id); err != nil {
+		return err
+	}
+	if with.Priority != "" {
+		if err := c.SetPriority(id, with.Priority); err != nil {
+			return err
+		}
+	}
+	if err := c.ClearTags(id); err != nil {
+		return err
+	}
+	if err := c.AddTags(id, with.Tags...); err != nil {
+		return err
+	}
+	i, _ := c.Index(id)
+	c.Items[i].Due = with.Due
+	if with.Recur != nil {
+		if err := with.Recur.validate(); err != nil {
+			return err
+		}
+	}
+	if err := c.SetRecurrence(id, with.Recur); err != nil {
+		return err
+	}
+	if with.Parent != c.Items[i].Parent {
+		if err := c.SetParent(id, with.Parent); err != nil {
+			return err
+		}
+	}
+	if with.ListName() != c.Items[i].ListName() {
+		if err := c.Move(id, with.ListName()); err != nil {
+			return err
+		}
+	}
+	c.Items[i].DependsOn = nil
+	for _, dep := range with.DependsOn {
+		if err := c.AddDependency(id, dep); err != nil {
+			return err
+		}
+	}
+	if status := strings.ToLower(with.Status); status != c.Items[i].Status {
+		if err := c.SetStatus(id, status, w); err != nil {
+			return err
+		}
+	}
+	*l = c
+	return nil
+}
+
+// MaxRange is the largest number of IDs ParseRange accepts, so a
+// typo such as 1-1000000000 does not allocate billions of IDs
+const MaxRange = 10000
+
+// ParseRange parses a comma separated list of IDs and inclusive
+// ranges of IDs, such as 1-5,8,10-12. It returns the IDs in the
+// order given, without duplicates, and refuses more than MaxRange IDs
+func ParseRange(s string) ([]int, error) {
+	var ids []int
+	seen := map[int]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil || first < 1 {
+			return nil, fmt.Errorf("%w: %q is not a positive ID", ErrInvalidRange, from)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(strings.TrimSpace(to)); err != nil || last < first {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidRange, part)
+			}
+		}
+		if last-first >= MaxRange-len(ids) {
+			return nil, fmt.Errorf("%w: more than %d IDs", ErrInvalidRange, MaxRange)
+		}
+		// Counting from first rather than comparing with last keeps
+		// ranges ending at the largest int from wrapping around
+		for n := 0; n <= last-first; n++ {
+			if id := first + n; !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// Apply calls fn for each of the given IDs and keeps the changes only
+// if all of them succeed, so the list is either changed for every item
+// or left untouched. IDs already deleted by fn for an earlier ID, such
+// as the subtasks of a deleted item, are skipped
+func (l *List) Apply(ids []int, fn func(l *List, id int) error) error {
+	for _, id := range ids {
+		if _, err := l.Index(id); err != nil {
+			return err
+		}
+	}
+	c := l.Clone()
+	for _, id := range ids {
+		if _, err := c.Index(id); err != nil {
+			continue
+		}
+		if err := fn(&c, id); err != nil {
+			return err
+		}
+	}
+	*l = c
+	return nil
+}
+
+// CompleteAll completes the items with the given IDs like Complete,
+// in an order satisfying their dependencies on each other
+func (l *List) CompleteAll(ids []int) error {
+	return l.Apply(ids, func(l *List, id int) error {
+		return l.completeAfter(id, ids, nil)
+	})
+}
+
+// completeAfter completes the item with the given ID once its open
+// dependencies among ids are completed. seen holds the IDs being
+// completed already, to stop at dependency cycles
+func (l *List) completeAfter(id int, ids, seen []int) error {
+	seen = append(seen, id)
+	for _, dep := range l.Blockers(id) {
+		if slices.Contains(ids, dep) && !slices.Contains(seen, dep) {
+			if err := l.completeAfter(dep, ids, seen); err != nil {
+				return err
+			}
+		}
+	}
+	if i, _ := l.Index(id); l.Items[i].Done() {
+		return nil
+	}
+	return l.Complete(id)
+}
@@ -0,0 +1,170 @@
+package todo_test
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+)
+
+func TestList_Edit(t *testing.T) {
+	l := todo.List{}
+	l.Add("Wrte report")
+	created := l.Items[0].CreatedAt
+
+	require.NoError(t, l.Edit(1, "  Write report "))
+	assert.Equal(t, "Write report", l.Items[0].Task)
+	assert.Equal(t, created, l.Items[0].CreatedAt, "expected the creation time to be kept")
+
+	assert.ErrorIs(t, l.Edit(1, " "), todo.ErrEmptyTask)
+	assert.ErrorIs(t, l.Edit(1, "Two\nlines"), todo.ErrEmptyTask)
+	assert.ErrorIs(t, l.Edit(2, "Task"), todo.ErrNotExists)
+}
+
+func TestList_Reopen(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	assert.ErrorIs(t, l.Reopen(1), todo.ErrNotCompleted)
+
+	require.NoError(t, l.Complete(1))
+	require.NoError(t, l.Reopen(1))
+	assert.Equal(t, todo.StatusTodo, l.Items[0].Status)
+	assert.True(t, l.Items[0].CompletedAt.IsZero())
+}
+
+func TestList_ReopenRecurring(t *testing.T) {
+	l := todo.List{}
+	id := l.Add("Water plants")
+	r, err := todo.ParseRecurrence("after:3")
+	require.NoError(t, err)
+	require.NoError(t, l.SetRecurrence(id, r))
+
+	require.NoError(t, l.Complete(id))
+	require.NoError(t, l.Reopen(id))
+	require.NoError(t, l.Complete(id))
+	require.Len(t, l.Items, 2, "expected completing again to keep the occurrence added before")
+	assert.Equal(t, 2, l.Items[0].Spawned)
+
+	require.NoError(t, l.Reopen(id))
+	require.NoError(t, l.Delete(2))
+	require.NoError(t, l.Complete(id))
+	require.Len(t, l.Items, 2, "expected a new occurrence once the previous one is deleted")
+	assert.Equal(t, 3, l.Items[1].ID)
+}
+
+func TestList_Replace(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	require.NoError(t, l.SetPriority(2, "A"))
+	created := l.Items[1].CreatedAt
+
+	require.NoError(t, l.Replace(2, []byte(`{"Task": "Second", "Tags": ["work"], "DependsOn": [1]}`), todo.DefaultWorkflow))
+	assert.Equal(t, "Second", l.Items[1].Task)
+	assert.Equal(t, []string{"work"}, l.Items[1].Tags)
+	assert.Empty(t, l.Items[1].Priority, "expected the missing priority to be cleared")
+	assert.True(t, created.Equal(l.Items[1].CreatedAt), "expected the creation time to be kept")
+	assert.Equal(t, 2, l.Items[1].ID)
+
+	t.Run("Status", func(t *testing.T) {
+		assert.ErrorIs(t, l.Replace(2, []byte(`{"Task": "Second", "Done": true, "DependsOn": [1]}`), todo.DefaultWorkflow), todo.ErrBlocked)
+		require.NoError(t, l.Replace(1, []byte(`{"Task": "Task 1", "Done": true}`), todo.DefaultWorkflow))
+		assert.True(t, l.Items[0].Done())
+		assert.False(t, l.Items[0].CompletedAt.IsZero(), "expected the completion time to be set")
+		require.NoError(t, l.Replace(1, []byte(`{"Task": "Task 1", "Status": "todo"}`), todo.DefaultWorkflow))
+		assert.False(t, l.Items[0].Done())
+		assert.Len(t, l.Items[0].Transitions, 2)
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		require.NoError(t, l.Replace(2, []byte(`{"Task": "Second", "Tags": ["x", "x", "", " work "], "DependsOn": [1]}`), todo.DefaultWorkflow))
+		assert.Equal(t, []string{"work", "x"}, l.Items[1].Tags)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		before := l.Clone()
+		for _, tc := range []struct {
+			data string
+			err  error
+		}{
+			{`{"Task": "Task 1", "ID": 3}`, todo.ErrInvalidItem},
+			{`{"Task": 1}`, todo.ErrInvalidItem},
+			{`{"Task": " "}`, todo.ErrEmptyTask},
+			{`{"Task": "Task 1", "Priority": "AB"}`, todo.ErrInvalidPriority},
+			{`{"Task": "Task 1", "Parent": 1}`, todo.ErrInvalidParent},
+			{`{"Task": "Task 1", "DependsOn": [2]}`, todo.ErrDependencyCycle},
+			{`{"Task": "Task 1", "DependsOn": [5]}`, todo.ErrInvalidDep},
+			{`{"Task": "Task 1", "List": "my list"}`, todo.ErrInvalidListName},
+			{`{"Task": "Task 1", "Status": "banana"}`, todo.ErrInvalidStatus},
+			{`{"Task": "Task 1", "Recur": {"Kind": "fortnightly"}}`, todo.ErrInvalidRecurrence},
+			{`{"Task": "Task 1", "Recur": {"Kind": "weekly", "Weekdays": [9]}}`, todo.ErrInvalidRecurrence},
+			{`{"Task": "Task 1", "Recur": {"Kind": "after"}}`, todo.ErrInvalidRecurrence},
+		} {
+			assert.ErrorIs(t, l.Replace(1, []byte(tc.data), todo.DefaultWorkflow), tc.err, tc.data)
+		}
+		assert.ErrorIs(t, l.Replace(3, []byte(`{"Task": "Task 3"}`), todo.DefaultWorkflow), todo.ErrNotExists)
+		assert.Equal(t, before, l.Clone(), "expected invalid items to leave the list untouched")
+	})
+}
+
+func TestParseRange(t *testing.T) {
+	ids, err := todo.ParseRange("1-3, 8,10-12,2")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 8, 10, 11, 12}, ids)
+
+	ids, err = todo.ParseRange("9223372036854775806-9223372036854775807")
+	require.NoError(t, err)
+	assert.Equal(t, []int{math.MaxInt64 - 1, math.MaxInt64}, ids)
+
+	ids, err = todo.ParseRange(fmt.Sprintf("1-%d", todo.MaxRange))
+	require.NoError(t, err)
+	assert.Len(t, ids, todo.MaxRange)
+
+	for _, s := range []string{"", "a", "0", "3-1", "1-", "-2", "1,,2", "1-2-3",
+		fmt.Sprintf("2-%d", todo.MaxRange+1) + ",1", "1-9223372036854775807"} {
+		_, err := todo.ParseRange(s)
+		assert.ErrorIs(t, err, todo.ErrInvalidRange, "expected %q to be rejected", s)
+	}
+}
+
+func TestList_Apply(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	_, err := l.AddSub(1, "Task 3")
+	require.NoError(t, err)
+	l.Add("Task 4")
+
+	t.Run("Atomic", func(t *testing.T) {
+		assert.ErrorIs(t, l.Apply([]int{1, 5}, (*todo.List).Delete), todo.ErrNotExists)
+		assert.Len(t, l.Items, 4)
+		require.NoError(t, l.Complete(2))
+		assert.ErrorIs(t, l.Apply([]int{1, 2}, (*todo.List).Reopen), todo.ErrNotCompleted)
+		assert.True(t, l.Items[1].Done(), "expected the list to be left untouched")
+	})
+	t.Run("Subtasks", func(t *testing.T) {
+		require.NoError(t, l.Apply([]int{1, 3}, (*todo.List).Delete),
+			"expected subtasks deleted with their parent to be skipped")
+		require.Len(t, l.Items, 2)
+		assert.Equal(t, 2, l.Items[0].ID)
+	})
+}
+
+func TestList_CompleteAll(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	l.Add("Task 3")
+	require.NoError(t, l.AddDependency(1, 2))
+	require.NoError(t, l.AddDependency(2, 3))
+
+	assert.ErrorIs(t, l.CompleteAll([]int{1, 2}), todo.ErrBlocked)
+	assert.False(t, l.Items[1].Done())
+
+	require.NoError(t, l.CompleteAll([]int{1, 2, 3}))
+	for _, value := range l.Items {
+		assert.True(t, value.Done(), "expected %d to be completed", value.ID)
+	}
+}
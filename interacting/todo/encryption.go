@@ -0,0 +1,258 @@
+package todo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/scrypt"
+	"io"
+	"os"
+	"sync"
+)
+
+var (
+	ErrPassphraseRequired = errors.New("the file is encrypted, a passphrase is required")
+	ErrDecrypt            = errors.New("unable to decrypt, wrong passphrase or corrupted file")
+)
+
+// Encrypted files start with a header made of encMagic, the format
+// version, the scrypt salt and the AES-GCM nonce. The whole header is
+// authenticated along with the encrypted JSON data
+const (
+	encMagic   = "TODOENC"
+	encVersion = 1
+	saltSize   = 16
+	headerSize = len(encMagic) + 1 + saltSize + 12
+)
+
+// scrypt parameters recommended for interactive logins
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keySize = 32
+)
+
+// Option configures how a List is read and saved
+type Option func(*options)
+
+type options struct {
+	keys *keyring
+}
+
+// WithPassphrase encrypts the saved data with a key derived from
+// passphrase and decrypts encrypted data read back.
+// An empty passphrase saves the data unencrypted
+func WithPassphrase(passphrase string) Option {
+	k := &keyring{passphrase: passphrase, keys: map[string][]byte{}}
+	return func(o *options) {
+		if passphrase != "" {
+			o.keys = k
+		}
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// keyring derives keys from a passphrase, caching them by salt since
+// deriving a key is deliberately slow
+type keyring struct {
+	mu         sync.Mutex
+	passphrase string
+	salt       []byte
+	keys       map[string][]byte
+}
+
+// key returns the key for salt
+func (k *keyring) key(salt []byte) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, ok := k.keys[string(salt)]; ok {
+		return key, nil
+	}
+	key, err := scrypt.Key([]byte(k.passphrase), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	k.keys[string(salt)] = key
+	return key, nil
+}
+
+// newSalt returns the salt used to encrypt, which is generated once
+// and reused so saving does not derive a new key every time
+func (k *keyring) newSalt() ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, err
+		}
+		k.salt = salt
+	}
+	return k.salt, nil
+}
+
+// isEncrypted reports whether data starts with the encryption header
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encMagic))
+}
+
+// IsEncrypted reports whether the file name is encrypted.
+// A missing file is not encrypted
+func IsEncrypted(name string) (bool, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+	magic := make([]byte, len(encMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false, nil
+	}
+	return isEncrypted(magic), nil
+}
+
+// seal encrypts data when a passphrase is set and returns it unchanged otherwise
+func (o options) seal(data []byte) ([]byte, error) {
+	if o.keys == nil {
+		return data, nil
+	}
+	salt, err := o.keys.newSalt()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, headerSize)
+	header = append(header, encMagic...)
+	header = append(header, encVersion)
+	header = append(header, salt...)
+	nonce := make([]byte, headerSize-len(header))
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	header = append(header, nonce...)
+
+	aead, err := o.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(header, nonce, data, header), nil
+}
+
+// open decrypts encrypted data and returns plaintext data unchanged
+func (o options) open(data []byte) ([]byte, error) {
+	if !isEncrypted(data) {
+		return data, nil
+	}
+	if o.keys == nil {
+		return nil, ErrPassphraseRequired
+	}
+	if len(data) < headerSize {
+		return nil, ErrDecrypt
+	}
+	if v := data[len(encMagic)]; v != encVersion {
+		return nil, fmt.Errorf("unsupported encryption version %d", v)
+	}
+	salt := data[len(encMagic)+1 : len(encMagic)+1+saltSize]
+	nonce := data[len(encMagic)+1+saltSize : headerSize]
+	aead, err := o.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, nonce, data[headerSize:], data[:headerSize])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+// aead returns the AES-GCM cipher keyed for salt
+func (o options) aead(salt []byte) (cipher.AEAD, error) {
+	key, err := o.keys.key(salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ChangePassphrase decrypts the file name with the old passphrase and
+// encrypts it again with the new one while holding the lock of name.
+// Related files guarded by the same lock, like the journal of the
+// list, are changed as well. An empty old passphrase reads plaintext
+// files and an empty new passphrase saves them unencrypted
+func ChangePassphrase(name, old, new string, related ...string) error {
+	return ChangePassphrases(old, new, append([]string{name}, related...))
+}
+
+// ChangePassphrases is ChangePassphrase for several groups of files,
+// each one a file followed by the related files guarded by its lock.
+// The locks are taken in the order of the groups, and every file is
+// changed or, when saving one fails, every file is left as it was
+func ChangePassphrases(old, new string, groups ...[]string) error {
+	var names []string
+	for _, g := range groups {
+		if len(g) == 0 {
+			continue
+		}
+		unlock, err := lockFile(g[0])
+		if err != nil {
+			return fmt.Errorf("unable to lock the file name %s: %w", g[0], err)
+		}
+		defer unlock()
+		names = append(names, g...)
+	}
+
+	from := newOptions([]Option{WithPassphrase(old)})
+	to := newOptions([]Option{WithPassphrase(new)})
+	// Decrypt and encrypt everything first so a wrong passphrase
+	// changes nothing
+	var originals, sealed [][]byte
+	var changed []string
+	for _, n := range names {
+		data, err := os.ReadFile(n)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		plain, err := from.open(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", n, err)
+		}
+		s, err := to.seal(plain)
+		if err != nil {
+			return err
+		}
+		changed, originals, sealed = append(changed, n), append(originals, data), append(sealed, s)
+	}
+	for i, n := range changed {
+		if err := writeFileAtomic(n, sealed[i], 0644); err != nil {
+			// Put back the files already saved, so they all keep
+			// the same passphrase
+			for j := i - 1; j >= 0; j-- {
+				if rerr := writeFileAtomic(changed[j], originals[j], 0644); rerr != nil {
+					return fmt.Errorf("%w, and %s is left with the new passphrase: %w", err, changed[j], rerr)
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
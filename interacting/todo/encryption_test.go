@@ -0,0 +1,108 @@
+package todo_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"pragprog.com/rggo/interacting/todo"
+	"strings"
+	"testing"
+)
+
+func TestList_SaveGetEncrypted(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ".todo.json")
+	l := todo.List{}
+	l.Add("Call ACME Corp")
+	require.NoError(t, l.Save(name, todo.WithPassphrase("secret")))
+
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "ACME", "expected the file to be encrypted")
+	encrypted, err := todo.IsEncrypted(name)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+
+	t.Run("Passphrase", func(t *testing.T) {
+		l2 := todo.List{}
+		require.NoError(t, l2.Get(name, todo.WithPassphrase("secret")))
+		require.Len(t, l2.Items, 1)
+		assert.Equal(t, "Call ACME Corp", l2.Items[0].Task)
+	})
+
+	t.Run("MissingPassphrase", func(t *testing.T) {
+		l2 := todo.List{}
+		assert.ErrorIs(t, l2.Get(name), todo.ErrPassphraseRequired)
+	})
+
+	t.Run("WrongPassphrase", func(t *testing.T) {
+		l2 := todo.List{}
+		assert.ErrorIs(t, l2.Get(name, todo.WithPassphrase("guess")), todo.ErrDecrypt)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		tampered := filepath.Join(t.TempDir(), ".todo.json")
+		data[len(data)-1] ^= 1
+		require.NoError(t, os.WriteFile(tampered, data, 0644))
+		l2 := todo.List{}
+		assert.ErrorIs(t, l2.Get(tampered, todo.WithPassphrase("secret")), todo.ErrDecrypt)
+	})
+
+	t.Run("Plaintext", func(t *testing.T) {
+		plain := filepath.Join(t.TempDir(), ".todo.json")
+		require.NoError(t, l.Save(plain))
+		l2 := todo.List{}
+		require.NoError(t, l2.Get(plain, todo.WithPassphrase("secret")), "expected plaintext files to load")
+		assert.Len(t, l2.Items, 1)
+	})
+}
+
+func TestChangePassphrase(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ".todo.json")
+	opt := todo.WithPassphrase("old")
+	j := todo.NewJournal(todo.NewFileStore(name, opt), name+".journal", opt)
+	require.NoError(t, j.Update(func(l *todo.List) error {
+		l.Add("Call ACME Corp")
+		return nil
+	}))
+	journal, err := os.ReadFile(name + ".journal")
+	require.NoError(t, err)
+	assert.NotContains(t, string(journal), "ACME", "expected the journal to be encrypted")
+
+	assert.ErrorIs(t, todo.ChangePassphrase(name, "wrong", "new", name+".journal"), todo.ErrDecrypt)
+	require.NoError(t, todo.ChangePassphrase(name, "old", "new", name+".journal"))
+
+	opt = todo.WithPassphrase("new")
+	j = todo.NewJournal(todo.NewFileStore(name, opt), name+".journal", opt)
+	l := todo.List{}
+	require.NoError(t, j.Load(&l))
+	assert.Len(t, l.Items, 1)
+	h, err := j.History()
+	require.NoError(t, err)
+	require.Len(t, h, 1)
+
+	require.NoError(t, todo.ChangePassphrase(name, "new", "", name+".journal"))
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(data), "ACME"), "expected an empty passphrase to decrypt the file")
+}
+
+func TestChangePassphraseRollback(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, ".todo.json")
+	// The temporary file saving it would get a name too long
+	long := filepath.Join(dir, strings.Repeat("n", 250))
+	opt := todo.WithPassphrase("old")
+	l := todo.List{}
+	l.Add("Call ACME Corp")
+	require.NoError(t, l.Save(name, opt))
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(long, data, 0644))
+
+	assert.Error(t, todo.ChangePassphrases("old", "new", []string{name}, []string{long}))
+	for _, n := range []string{name, long} {
+		l := todo.List{}
+		assert.NoError(t, l.Get(n, opt), "expected %s to keep the old passphrase", filepath.Base(n))
+	}
+}
@@ -0,0 +1,412 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// Operations recorded in the journal
+const (
+	OpAdd      = "add"
+	OpComplete = "complete"
+	OpDelete   = "delete"
+	OpEdit     = "edit"
+	OpUndo     = "undo"
+	OpRedo     = "redo"
+)
+
+// Change records a single item before and after a mutation.
+// Before is nil for added items and After is nil for deleted ones
+type Change struct {
+	Op     string
+	Index  int
+	Before *item `json:",omitempty"`
+	After  *item `json:",omitempty"`
+}
+
+// Entry is a journal record of all changes made by one update.
+// Undo and redo entries reference the entry they revert or replay in Ref
+type Entry struct {
+	Seq     int
+	Time    time.Time
+	Op      string
+	Ref     int `json:",omitempty"`
+	Changes []Change
+}
+
+// String summarizes the entry in a single line
+func (e Entry) String() string {
+	var tasks []string
+	for _, c := range e.Changes {
+		i := c.After
+		if i == nil {
+			i = c.Before
+		}
+		tasks = append(tasks, fmt.Sprintf("%d: %s", i.ID, i.Task))
+	}
+	op := e.Op
+	if e.Ref > 0 {
+		op = fmt.Sprintf("%s #%d", e.Op, e.Ref)
+	}
+	return fmt.Sprintf("#%d %s %s %s", e.Seq, e.Time.Format(time.DateTime), op, strings.Join(tasks, ", "))
+}
+
+// Journal is a Store that records every update in an append-only
+// journal file so updates can be undone and redone.
+// Only the updates made through the Journal are recorded. Changes
+// saved with the bare Store, as todoServer does, cannot be undone,
+// and undoing an entry skips the changes that no longer apply
+type Journal struct {
+	Store
+	name string
+	opts options
+}
+
+// NewJournal wraps s, recording its updates in the journal file name.
+// Pass WithPassphrase to keep the journal encrypted like the list
+func NewJournal(s Store, name string, opts ...Option) *Journal {
+	return &Journal{
+		Store: s,
+		name:  name,
+		opts:  newOptions(opts),
+	}
+}
+
+// Update applies fn through the wrapped Store and journals the changes
+func (j *Journal) Update(fn func(*List) error) error {
+	return j.update("", func(l *List) (int, error) {
+		return 0, fn(l)
+	})
+}
+
+// Undo reverts the most recent update that was not undone yet.
+// The entry to revert is picked while the Store holds its lock,
+// so concurrent undos never revert the same entry twice
+func (j *Journal) Undo() (Entry, error) {
+	var target Entry
+	err := j.update(OpUndo, func(l *List) (int, error) {
+		done, _, err := j.stacks()
+		if err != nil {
+			return 0, err
+		}
+		if len(done) == 0 {
+			return 0, ErrNothingToUndo
+		}
+		target = done[len(done)-1]
+		l.revert(target.Changes)
+		return target.Seq, nil
+	})
+	return target, err
+}
+
+// Redo replays the most recently undone update
+func (j *Journal) Redo() (Entry, error) {
+	var target Entry
+	err := j.update(OpRedo, func(l *List) (int, error) {
+		_, undone, err := j.stacks()
+		if err != nil {
+			return 0, err
+		}
+		if len(undone) == 0 {
+			return 0, ErrNothingToRedo
+		}
+		target = undone[len(undone)-1]
+		l.replay(target.Changes)
+		return target.Seq, nil
+	})
+	return target, err
+}
+
+// History returns all the journal entries, oldest first
+func (j *Journal) History() ([]Entry, error) {
+	file, err := os.ReadFile(j.name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to open the journal %s: %w", j.name, err)
+	}
+	if file, err = j.opts.open(file); err != nil {
+		return nil, fmt.Errorf("%s: %w", j.name, err)
+	}
+	var entries []Entry
+	dec := json.NewDecoder(bytes.NewReader(file))
+	for {
+		var e Entry
+		if err := dec.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			return nil, fmt.Errorf("invalid journal entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+}
+
+// stacks replays the history returning the entries that can be
+// undone and the ones that can be redone, most recent last
+func (j *Journal) stacks() ([]Entry, []Entry, error) {
+	entries, err := j.History()
+	if err != nil {
+		return nil, nil, err
+	}
+	var done, undone []Entry
+	for _, e := range entries {
+		switch e.Op {
+		case OpUndo:
+			if len(done) > 0 {
+				undone = append(undone, done[len(done)-1])
+				done = done[:len(done)-1]
+			}
+		case OpRedo:
+			if len(undone) > 0 {
+				done = append(done, undone[len(undone)-1])
+				undone = undone[:len(undone)-1]
+			}
+		default:
+			done = append(done, e)
+			undone = nil
+		}
+	}
+	return done, undone, nil
+}
+
+// update applies fn and appends an entry with the resulting changes
+// to the journal while the Store still holds its lock. fn returns the
+// sequence number of the entry it reverts or replays, if any.
+// An empty op is derived from the changes themselves
+func (j *Journal) update(op string, fn func(*List) (int, error)) error {
+	return j.Store.Update(func(l *List) error {
+		before := l.Clone()
+		ref, err := fn(l)
+		if err != nil {
+			return err
+		}
+		changes := diff(before, *l)
+		if len(changes) == 0 {
+			return nil
+		}
+		if op == "" {
+			op = summarize(changes)
+		}
+		return j.append(Entry{
+			Time:    time.Now(),
+			Op:      op,
+			Ref:     ref,
+			Changes: changes,
+		})
+	})
+}
+
+// append writes e at the end of the journal with the next sequence number.
+// An encrypted journal is rewritten as a whole
+func (j *Journal) append(e Entry) error {
+	if j.opts.keys != nil {
+		entries, err := j.History()
+		if err != nil {
+			return err
+		}
+		e.Seq = len(entries) + 1
+		return j.rewrite(append(entries, e))
+	}
+	seq, err := j.lastSeq()
+	if err != nil {
+		return err
+	}
+	e.Seq = seq + 1
+	js, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("unable to encode the journal entry: %w", err)
+	}
+	f, err := os.OpenFile(j.name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open the journal %s: %w", j.name, err)
+	}
+	if _, err := f.Write(append(js, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// lastSeq returns the sequence number of the last entry of an
+// unencrypted journal, or 0 when it is empty. It only reads the end
+// of the file, going back until it holds the whole last entry
+func (j *Journal) lastSeq() (int, error) {
+	f, err := os.Open(j.name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("unable to open the journal %s: %w", j.name, err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := fi.Size()
+	for n := int64(4096); ; n *= 2 {
+		n = min(n, size)
+		buf := make([]byte, n)
+		if _, err := f.ReadAt(buf, size-n); err != nil {
+			return 0, fmt.Errorf("unable to read the journal %s: %w", j.name, err)
+		}
+		buf = bytes.TrimRight(buf, "\n")
+		i := bytes.LastIndexByte(buf, '\n')
+		if i < 0 && n < size {
+			continue
+		}
+		if len(buf) == 0 {
+			return 0, nil
+		}
+		var e Entry
+		if err := json.Unmarshal(buf[i+1:], &e); err != nil {
+			return 0, fmt.Errorf("invalid journal entry: %w", err)
+		}
+		return e.Seq, nil
+	}
+}
+
+// rewrite encrypts and saves all the journal entries
+func (j *Journal) rewrite(entries []Entry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("unable to encode the journal entry: %w", err)
+		}
+	}
+	data, err := j.opts.seal(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("unable to encrypt the journal: %w", err)
+	}
+	return writeFileAtomic(j.name, data, 0644)
+}
+
+// Clone returns a deep copy of the list, sharing nothing with it
+func (l *List) Clone() List {
+	c := List{Items: slices.Clone(l.Items), LastID: l.LastID}
+	for i, value := range c.Items {
+		c.Items[i].Tags = slices.Clone(value.Tags)
+		c.Items[i].Transitions = slices.Clone(value.Transitions)
+		c.Items[i].DependsOn = slices.Clone(value.DependsOn)
+		if value.Recur != nil {
+			r := *value.Recur
+			r.Weekdays = slices.Clone(r.Weekdays)
+			c.Items[i].Recur = &r
+		}
+	}
+	return c
+}
+
+// diff returns the changes that turn before into after.
+// Deletions come first from the last position backwards,
+// followed by edits and additions in list order
+func diff(before, after List) []Change {
+	var changes []Change
+	inAfter := map[int]bool{}
+	for _, value := range after.Items {
+		inAfter[value.ID] = true
+	}
+	positions := map[int]int{}
+	for i := len(before.Items) - 1; i >= 0; i-- {
+		positions[before.Items[i].ID] = i
+		if !inAfter[before.Items[i].ID] {
+			b := before.Items[i]
+			changes = append(changes, Change{Op: OpDelete, Index: i, Before: &b})
+		}
+	}
+	for i, value := range after.Items {
+		a := value
+		p, ok := positions[value.ID]
+		if !ok {
+			changes = append(changes, Change{Op: OpAdd, Index: i, After: &a})
+			continue
+		}
+		b := before.Items[p]
+		if !equal(b, a) {
+			op := OpEdit
+			if !b.Done() && a.Done() {
+				op = OpComplete
+			}
+			changes = append(changes, Change{Op: op, Index: i, Before: &b, After: &a})
+		}
+	}
+	return changes
+}
+
+// summarize returns the operation shared by all changes
+// or the distinct operations joined by "+"
+func summarize(changes []Change) string {
+	var ops []string
+	seen := map[string]bool{}
+	for _, c := range changes {
+		if !seen[c.Op] {
+			seen[c.Op] = true
+			ops = append(ops, c.Op)
+		}
+	}
+	return strings.Join(ops, "+")
+}
+
+// equal compares two items through their JSON encoding
+func equal(a, b item) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// replay applies changes to the list.
+// Changes that no longer apply, like adding an item already in
+// the list, are skipped
+func (l *List) replay(changes []Change) {
+	for _, c := range changes {
+		l.applyChange(c.Index, c.Before, c.After)
+	}
+}
+
+// revert undoes changes on the list, skipping the ones that no longer apply
+func (l *List) revert(changes []Change) {
+	for i := len(changes) - 1; i >= 0; i-- {
+		c := changes[i]
+		l.applyChange(c.Index, c.After, c.Before)
+	}
+}
+
+// applyChange turns the item from into to, inserting to at index
+// when from is nil and removing from when to is nil
+func (l *List) applyChange(index int, from, to *item) {
+	switch {
+	case from == nil:
+		if _, err := l.Index(to.ID); err == nil {
+			return
+		}
+		ls := l.Items
+		if index < 0 || index > len(ls) {
+			index = len(ls)
+		}
+		ls = append(ls, item{})
+		copy(ls[index+1:], ls[index:])
+		ls[index] = *to
+		l.Items = ls
+	case to == nil:
+		l.Delete(from.ID)
+	default:
+		if i, err := l.Index(to.ID); err == nil {
+			l.Items[i] = *to
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package todo_test
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"pragprog.com/rggo/interacting/todo"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ".todo.json")
+	j := todo.NewJournal(todo.NewFileStore(name), name+".journal")
+	defer j.Close()
+
+	tasks := func(t *testing.T) []string {
+		t.Helper()
+		l := todo.List{}
+		require.NoError(t, j.Load(&l))
+		var ts []string
+		for _, value := range l.Items {
+			ts = append(ts, value.Task)
+		}
+		return ts
+	}
+
+	require.NoError(t, j.Update(func(l *todo.List) error {
+		l.Add("Task 1")
+		l.Add("Task 2")
+		l.Add("Task 3")
+		return nil
+	}))
+	require.NoError(t, j.Update(func(l *todo.List) error {
+		return l.Delete(2)
+	}))
+	require.NoError(t, j.Update(func(l *todo.List) error {
+		return l.Complete(3)
+	}))
+
+	t.Run("Undo", func(t *testing.T) {
+		e, err := j.Undo()
+		require.NoError(t, err)
+		assert.Equal(t, todo.OpComplete, e.Op)
+		e, err = j.Undo()
+		require.NoError(t, err)
+		assert.Equal(t, todo.OpDelete, e.Op)
+		assert.Equal(t, []string{"Task 1", "Task 2", "Task 3"}, tasks(t), "expected deleted task restored in place")
+		l := todo.List{}
+		require.NoError(t, j.Load(&l))
+		assert.False(t, l.Items[2].Done(), "expected completion undone")
+	})
+
+	t.Run("Redo", func(t *testing.T) {
+		e, err := j.Redo()
+		require.NoError(t, err)
+		assert.Equal(t, todo.OpDelete, e.Op)
+		assert.Equal(t, []string{"Task 1", "Task 3"}, tasks(t))
+	})
+
+	t.Run("NewUpdateClearsRedo", func(t *testing.T) {
+		require.NoError(t, j.Update(func(l *todo.List) error {
+			l.Add("Task 4")
+			return nil
+		}))
+		_, err := j.Redo()
+		assert.ErrorIs(t, err, todo.ErrNothingToRedo)
+	})
+
+	t.Run("History", func(t *testing.T) {
+		h, err := j.History()
+		require.NoError(t, err)
+		var ops []string
+		for _, e := range h {
+			ops = append(ops, e.Op)
+		}
+		exp := []string{"add", "delete", "complete", "undo", "undo", "redo", "add"}
+		assert.Equal(t, exp, ops)
+		assert.Equal(t, 7, h[6].Seq)
+		assert.Equal(t, 2, h[4].Ref)
+	})
+
+	t.Run("UndoAll", func(t *testing.T) {
+		for {
+			if _, err := j.Undo(); err != nil {
+				assert.ErrorIs(t, err, todo.ErrNothingToUndo)
+				break
+			}
+		}
+		assert.Empty(t, tasks(t))
+	})
+}
+
+func TestJournal_ConcurrentUndo(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ".todo.json")
+	j := todo.NewJournal(todo.NewFileStore(name), name+".journal")
+	defer j.Close()
+
+	const updates = 10
+	for i := 0; i < updates; i++ {
+		require.NoError(t, j.Update(func(l *todo.List) error {
+			// Long tasks make entries larger than a single read
+			// of the end of the journal
+			l.Add(strings.Repeat(fmt.Sprint(i), 5000))
+			return nil
+		}))
+	}
+
+	var wg sync.WaitGroup
+	refs := make(chan int, updates)
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e, err := j.Undo()
+			assert.NoError(t, err)
+			refs <- e.Seq
+		}()
+	}
+	wg.Wait()
+	close(refs)
+	seen := map[int]bool{}
+	for ref := range refs {
+		assert.False(t, seen[ref], "expected entry %d to be undone only once", ref)
+		seen[ref] = true
+	}
+	l := todo.List{}
+	require.NoError(t, j.Load(&l))
+	assert.Empty(t, l.Items)
+
+	h, err := j.History()
+	require.NoError(t, err)
+	require.Len(t, h, 2*updates)
+	for i, e := range h {
+		assert.Equal(t, i+1, e.Seq)
+	}
+}
+
+func TestList_Clone(t *testing.T) {
+	l := todo.List{}
+	id := l.Add("Task 1")
+	require.NoError(t, l.AddTags(id, "work"))
+	r, err := todo.ParseRecurrence("weekly:mon")
+	require.NoError(t, err)
+	require.NoError(t, l.SetRecurrence(id, r))
+
+	c := l.Clone()
+	assert.Equal(t, l, c)
+	c.Items[0].Tags[0] = "home"
+	c.Items[0].Recur.Weekdays[0] = time.Friday
+	assert.Equal(t, []string{"work"}, l.Items[0].Tags)
+	assert.Equal(t, "weekly:mon", l.Items[0].Recur.String())
+}
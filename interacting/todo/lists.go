@@ -0,0 +1,299 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrListExists      = errors.New("list already exists")
+	ErrListNotExists   = errors.New("list does not exist")
+	ErrInvalidListName = errors.New("invalid list name")
+)
+
+// DefaultList is the list items belong to unless they are added to or
+// moved to another one. Items in the default list keep an empty List
+// field, so files saved before named lists existed remain valid
+const DefaultList = "default"
+
+// ListName returns the name of the list the item belongs to
+func (i item) ListName() string {
+	if i.List == "" {
+		return DefaultList
+	}
+	return i.List
+}
+
+// validListName checks that name can be used as a list name
+func validListName(name string) error {
+	if name == "" || strings.ContainsFunc(name, unicode.IsSpace) || strings.Contains(name, ":") {
+		return fmt.Errorf("%w: %q", ErrInvalidListName, name)
+	}
+	return nil
+}
+
+// listField returns the value stored in the List field for name
+func listField(name string) string {
+	if name == DefaultList {
+		return ""
+	}
+	return name
+}
+
+// Names returns the names of the lists holding items, sorted,
+// with the default list first
+func (l *List) Names() []string {
+	var names []string
+	for _, value := range l.Items {
+		if !slices.Contains(names, value.ListName()) {
+			names = append(names, value.ListName())
+		}
+	}
+	sortNames(names)
+	return names
+}
+
+// In returns the items of the list with the given name
+func (l *List) In(name string) List {
+	items := List{from: l.whole()}
+	for _, value := range l.Items {
+		if value.ListName() == name {
+			items.Items = append(items.Items, value)
+		}
+	}
+	return items
+}
+
+// Move moves the item with the given ID, along with its subtasks,
+// to the list with the given name. A subtask moved on its own
+// leaves its parent and becomes a top level item
+func (l *List) Move(id int, name string) error {
+	if err := validListName(name); err != nil {
+		return err
+	}
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	if p, err := l.Index(l.Items[i].Parent); err == nil && l.Items[p].ListName() != name {
+		l.Items[i].Parent = 0
+	}
+	l.setList(append([]int{id}, l.descendants(id)...), name)
+	return nil
+}
+
+// RenameList moves all the items of the list old to the list new
+func (l *List) RenameList(old, new string) error {
+	if err := validListName(new); err != nil {
+		return err
+	}
+	var ids []int
+	for _, value := range l.Items {
+		if value.ListName() == old {
+			ids = append(ids, value.ID)
+		}
+	}
+	l.setList(ids, new)
+	return nil
+}
+
+// DeleteList deletes all the items of the list with the given name
+// and returns how many were deleted. Items of other lists drop their
+// dependencies on them, and their subtasks become top level items
+func (l *List) DeleteList(name string) int {
+	var ids []int
+	for _, value := range l.Items {
+		if value.ListName() == name {
+			ids = append(ids, value.ID)
+		}
+	}
+	l.Items = slices.DeleteFunc(l.Items, func(value item) bool {
+		return value.ListName() == name
+	})
+	l.dropDependencies(ids)
+	for i := range l.Items {
+		if slices.Contains(ids, l.Items[i].Parent) {
+			l.Items[i].Parent = 0
+		}
+	}
+	return len(ids)
+}
+
+// setList sets the list of the items with the given IDs
+func (l *List) setList(ids []int, name string) {
+	ls := l.Items
+	for i := range ls {
+		if slices.Contains(ids, ls[i].ID) {
+			ls[i].List = listField(name)
+		}
+	}
+}
+
+// sortNames sorts list names alphabetically with the default list first
+func sortNames(names []string) {
+	slices.SortFunc(names, func(a, b string) int {
+		switch {
+		case a == b:
+			return 0
+		case a == DefaultList:
+			return -1
+		case b == DefaultList:
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+}
+
+// Catalog records the names of the lists in a store, so lists exist
+// before any item is added to them, which list is active, after
+// how many days the completed items of each list are archived, the
+// workflow of the items and the templates of items added together
+type Catalog struct {
+	Active       string              `json:",omitempty"`
+	Names        []string            `json:",omitempty"`
+	ArchiveAfter map[string]int      `json:",omitempty"`
+	Workflow     Workflow            `json:",omitempty"`
+	Templates    map[string]Template `json:",omitempty"`
+}
+
+// Current returns the name of the active list
+func (c *Catalog) Current() string {
+	if c.Active == "" {
+		return DefaultList
+	}
+	return c.Active
+}
+
+// Sync adds the lists holding items in l to the catalog
+func (c *Catalog) Sync(l *List) {
+	for _, name := range l.Names() {
+		if !c.Has(name) {
+			c.Names = append(c.Names, name)
+		}
+	}
+	sortNames(c.Names)
+}
+
+// Has reports whether the list with the given name exists.
+// The default list always exists
+func (c *Catalog) Has(name string) bool {
+	return name == DefaultList || slices.Contains(c.Names, name)
+}
+
+// Create adds an empty list with the given name
+func (c *Catalog) Create(name string) error {
+	if err := validListName(name); err != nil {
+		return err
+	}
+	if c.Has(name) {
+		return fmt.Errorf("%w: %s", ErrListExists, name)
+	}
+	c.Names = append(c.Names, name)
+	sortNames(c.Names)
+	return nil
+}
+
+// Rename renames the list old to new, keeping it active if it was
+func (c *Catalog) Rename(old, new string) error {
+	if !c.Has(old) {
+		return fmt.Errorf("%w: %s", ErrListNotExists, old)
+	}
+	if old == DefaultList {
+		return fmt.Errorf("%w: the %s list cannot be renamed", ErrInvalidListName, DefaultList)
+	}
+	if err := c.Create(new); err != nil {
+		return err
+	}
+	c.Names = slices.DeleteFunc(c.Names, func(name string) bool { return name == old })
+	if c.Current() == old {
+		c.Active = listField(new)
+	}
+	if days, ok := c.ArchiveAfter[old]; ok {
+		delete(c.ArchiveAfter, old)
+		c.ArchiveAfter[new] = days
+	}
+	return nil
+}
+
+// Remove removes the list with the given name. Removing the active
+// list makes the default list active
+func (c *Catalog) Remove(name string) error {
+	if !c.Has(name) {
+		return fmt.Errorf("%w: %s", ErrListNotExists, name)
+	}
+	if name == DefaultList {
+		return fmt.Errorf("%w: the %s list cannot be deleted", ErrInvalidListName, DefaultList)
+	}
+	c.Names = slices.DeleteFunc(c.Names, func(n string) bool { return n == name })
+	if c.Current() == name {
+		c.Active = ""
+	}
+	delete(c.ArchiveAfter, name)
+	return nil
+}
+
+// Use makes the list with the given name the active one
+func (c *Catalog) Use(name string) error {
+	if !c.Has(name) {
+		return fmt.Errorf("%w: %s", ErrListNotExists, name)
+	}
+	c.Active = listField(name)
+	return nil
+}
+
+// Get reads the catalog from the provided file name.
+// A missing file is an empty catalog
+func (c *Catalog) Get(name string, opts ...Option) error {
+	file, err := os.ReadFile(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("unable to open the catalog %s: %w", name, err)
+	}
+	if len(file) == 0 {
+		return nil
+	}
+	if file, err = newOptions(opts).open(file); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return json.Unmarshal(file, c)
+}
+
+// Save encodes the catalog as JSON and saves it using the provided file name
+func (c *Catalog) Save(name string, opts ...Option) error {
+	js, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("unable to encode the catalog as JSON: %w", err)
+	}
+	data, err := newOptions(opts).seal(js)
+	if err != nil {
+		return fmt.Errorf("unable to encrypt the catalog: %w", err)
+	}
+	return writeFileAtomic(name, data, 0644)
+}
+
+// UpdateCatalog locks the provided file name, reads the catalog from it,
+// applies fn and saves the result before releasing the lock.
+// The catalog is not saved if fn returns an error
+func UpdateCatalog(name string, fn func(*Catalog) error, opts ...Option) error {
+	unlock, err := lockFile(name)
+	if err != nil {
+		return fmt.Errorf("unable to lock the file name %s: %w", name, err)
+	}
+	defer unlock()
+
+	c := &Catalog{}
+	if err := c.Get(name, opts...); err != nil {
+		return err
+	}
+	if err := fn(c); err != nil {
+		return err
+	}
+	return c.Save(name, opts...)
+}
@@ -0,0 +1,93 @@
+package todo_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+)
+
+func TestList_Lists(t *testing.T) {
+	l := todo.List{}
+	groceries := l.Add("Groceries")
+	release := l.Add("Release")
+	notes, err := l.AddSub(release, "Notes")
+	require.NoError(t, err)
+
+	t.Run("Move", func(t *testing.T) {
+		assert.ErrorIs(t, l.Move(release, "my work"), todo.ErrInvalidListName)
+		assert.ErrorIs(t, l.Move(100, "work"), todo.ErrNotExists)
+		require.NoError(t, l.Move(release, "work"))
+		assert.Equal(t, []string{todo.DefaultList, "work"}, l.Names())
+		work := l.In("work")
+		require.Len(t, work.Items, 2, "expected subtasks to move along")
+		assert.Equal(t, "  2: Release [0/1]\n    3: Notes\n", work.String())
+		assert.Equal(t, "work", work.Items[1].ListName())
+	})
+
+	t.Run("AddSub", func(t *testing.T) {
+		id, err := l.AddSub(notes, "Draft")
+		require.NoError(t, err)
+		i, err := l.Index(id)
+		require.NoError(t, err)
+		assert.Equal(t, "work", l.Items[i].ListName(), "expected subtask in the parent list")
+	})
+
+	t.Run("MoveSubtask", func(t *testing.T) {
+		require.NoError(t, l.Move(notes, todo.DefaultList))
+		i, err := l.Index(notes)
+		require.NoError(t, err)
+		assert.Equal(t, 0, l.Items[i].Parent, "expected subtask to leave its parent")
+		assert.Len(t, l.In(todo.DefaultList).Items, 3)
+		require.NoError(t, l.SetParent(notes, release))
+		assert.Len(t, l.In("work").Items, 3, "expected subtree to follow its new parent")
+	})
+
+	t.Run("RenameList", func(t *testing.T) {
+		require.NoError(t, l.RenameList("work", "job"))
+		assert.Equal(t, []string{todo.DefaultList, "job"}, l.Names())
+	})
+
+	t.Run("DeleteList", func(t *testing.T) {
+		require.NoError(t, l.AddDependency(groceries, release))
+		assert.Equal(t, 3, l.DeleteList("job"))
+		require.Len(t, l.Items, 1)
+		assert.Equal(t, groceries, l.Items[0].ID)
+		assert.Empty(t, l.Items[0].DependsOn, "expected dependencies on deleted items to be dropped")
+		assert.Len(t, l.Ready().Items, 1)
+	})
+}
+
+func TestCatalog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ".todo.json.lists")
+
+	err := todo.UpdateCatalog(name, func(c *todo.Catalog) error {
+		assert.Equal(t, todo.DefaultList, c.Current())
+		require.NoError(t, c.Create("work"))
+		assert.ErrorIs(t, c.Create("work"), todo.ErrListExists)
+		assert.ErrorIs(t, c.Create(""), todo.ErrInvalidListName)
+		assert.ErrorIs(t, c.Use("home"), todo.ErrListNotExists)
+		return c.Use("work")
+	})
+	require.NoError(t, err)
+
+	c := &todo.Catalog{}
+	require.NoError(t, c.Get(name))
+	assert.Equal(t, "work", c.Current())
+
+	l := todo.List{}
+	require.NoError(t, l.Move(l.Add("Call"), "home"))
+	c.Sync(&l)
+	assert.Equal(t, []string{"home", "work"}, c.Names)
+
+	require.NoError(t, c.Rename("work", "job"))
+	assert.Equal(t, "job", c.Current(), "expected renamed list to stay active")
+	assert.ErrorIs(t, c.Rename("job", "home"), todo.ErrListExists)
+	assert.ErrorIs(t, c.Rename(todo.DefaultList, "main"), todo.ErrInvalidListName)
+
+	require.NoError(t, c.Remove("job"))
+	assert.Equal(t, todo.DefaultList, c.Current())
+	assert.ErrorIs(t, c.Remove("job"), todo.ErrListNotExists)
+	assert.ErrorIs(t, c.Remove(todo.DefaultList), todo.ErrInvalidListName)
+}
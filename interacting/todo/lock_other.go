@@ -0,0 +1,9 @@
+//go:build !unix
+
+package todo
+
+// lockFile is a no-op on platforms without flock.
+// Saves are still atomic, but concurrent updates are not serialized
+func lockFile(name string) (func() error, error) {
+	return func() error { return nil }, nil
+}
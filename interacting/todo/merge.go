@@ -0,0 +1,235 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Conflict describes an item changed on both sides in ways Merge
+// cannot reconcile, in which case the merged list keeps our version
+// of the item, or a dependency dropped by Merge or Import as it
+// was on a missing item or closed a cycle
+type Conflict struct {
+	ID     int
+	Task   string
+	Reason string
+}
+
+// String summarizes the conflict in a single line
+func (c Conflict) String() string {
+	return fmt.Sprintf("%d: %s: %s", c.ID, c.Task, c.Reason)
+}
+
+// Merge reconciles ours and theirs, two versions of the list changed
+// independently since their common ancestor base:
+//
+//   - items added on either side are kept. Items both sides added
+//     with the same ID but a different task are both kept, and
+//     theirs gets a new ID
+//   - fields changed on one side only take the changed value.
+//     Completion wins over other status changes, an item completed
+//     on both sides keeps the earliest completion and the status
+//     history keeps the transitions of both sides
+//   - items deleted on one side are deleted unless the other side
+//     changed more than their completion
+//
+// Fields changed differently on both sides, and items deleted on one
+// side but edited on the other, are reported as conflicts and keep
+// our version. Dependencies left on deleted items or closing a cycle
+// are dropped and reported as conflicts as well.
+// An error means an item could not be merged at all
+func Merge(base, ours, theirs List) (List, []Conflict, error) {
+	baseItems, ourItems, theirItems := byID(base), byID(ours), byID(theirs)
+	nextID := max(base.nextID(), ours.nextID(), theirs.nextID())
+
+	merged := List{}
+	var conflicts []Conflict
+	// theirSpawned are the merged items pointing at the occurrence
+	// they spawned, which is renumbered along with their additions
+	var theirSpawned []int
+	for _, o := range ours.Items {
+		b, inBase := baseItems[o.ID]
+		t, inTheirs := theirItems[o.ID]
+		switch {
+		case !inBase:
+			merged.Items = append(merged.Items, o)
+		case !inTheirs:
+			if !completedOnly(b, o) {
+				merged.Items = append(merged.Items, o)
+				conflicts = append(conflicts, Conflict{o.ID, o.Task, "deleted by them but changed by us"})
+			}
+		default:
+			m, fields, err := mergeItem(b, o, t)
+			if err != nil {
+				return List{}, nil, fmt.Errorf("unable to merge item %d: %w", o.ID, err)
+			}
+			if m.Spawned != o.Spawned {
+				theirSpawned = append(theirSpawned, len(merged.Items))
+			}
+			merged.Items = append(merged.Items, m)
+			if len(fields) > 0 {
+				reason := "both sides changed " + strings.Join(fields, ", ")
+				conflicts = append(conflicts, Conflict{o.ID, o.Task, reason})
+			}
+		}
+	}
+
+	// Their additions go after ours, renumbered when we added
+	// a different item with the same ID
+	renumbered := map[int]int{}
+	added := List{}
+	for _, t := range theirs.Items {
+		b, inBase := baseItems[t.ID]
+		o, inOurs := ourItems[t.ID]
+		switch {
+		case !inBase && !inOurs:
+			added.Items = append(added.Items, t)
+		case !inBase && o.Task != t.Task:
+			renumbered[t.ID] = nextID
+			t.ID = nextID
+			nextID++
+			added.Items = append(added.Items, t)
+		case inBase && !inOurs:
+			if !completedOnly(b, t) {
+				added.Items = append(added.Items, t)
+				conflicts = append(conflicts, Conflict{t.ID, t.Task, "deleted by us but changed by them"})
+			}
+		}
+	}
+	for i := range added.Items {
+		if id, ok := renumbered[added.Items[i].Parent]; ok {
+			added.Items[i].Parent = id
+		}
+		if id, ok := renumbered[added.Items[i].Spawned]; ok {
+			added.Items[i].Spawned = id
+		}
+		added.Items[i].DependsOn = renumberIDs(added.Items[i].DependsOn, renumbered)
+	}
+	for _, i := range theirSpawned {
+		if id, ok := renumbered[merged.Items[i].Spawned]; ok {
+			merged.Items[i].Spawned = id
+		}
+	}
+	merged.Items = append(merged.Items, added.Items...)
+	merged.LastID = nextID - 1
+	conflicts = append(conflicts, merged.pruneDependencies()...)
+	return merged, conflicts, nil
+}
+
+// byID maps the IDs of the list to its items
+func byID(l List) map[int]item {
+	items := map[int]item{}
+	for _, value := range l.Items {
+		items[value.ID] = value
+	}
+	return items
+}
+
+// completedOnly reports whether changed differs from base by
+// nothing but being completed, so deleting it loses nothing
+func completedOnly(base, changed item) bool {
+	if !changed.Done() {
+		return equal(base, changed)
+	}
+	base.Status, base.CompletedAt, base.Transitions = changed.Status, changed.CompletedAt, changed.Transitions
+	return equal(base, changed)
+}
+
+// mergeItem merges the changes both sides made to base field by field.
+// It returns the merged item and the fields changed differently on
+// both sides, which keep our value
+func mergeItem(base, ours, theirs item) (item, []string, error) {
+	var all [3]map[string]json.RawMessage
+	for n, i := range []item{base, ours, theirs} {
+		m, err := fields(i)
+		if err != nil {
+			return item{}, nil, err
+		}
+		all[n] = m
+	}
+	b, o, t := all[0], all[1], all[2]
+	var keys []string
+	for _, m := range all {
+		for k := range m {
+			if !slices.Contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	slices.Sort(keys)
+
+	merged := map[string]json.RawMessage{}
+	var conflicts []string
+	for _, k := range keys {
+		value := o[k]
+		switch {
+		case bytes.Equal(o[k], t[k]), bytes.Equal(t[k], b[k]):
+		case bytes.Equal(o[k], b[k]):
+			value = t[k]
+		case k == "CompletedAt":
+			value = earliest(o[k], t[k])
+		case k == "Status" && (ours.Done() || theirs.Done()):
+			value = json.RawMessage(`"` + StatusDone + `"`)
+		case k == "Transitions":
+			var err error
+			if value, err = mergeTransitions(base, ours, theirs); err != nil {
+				return item{}, nil, err
+			}
+		default:
+			conflicts = append(conflicts, k)
+		}
+		if value != nil {
+			merged[k] = value
+		}
+	}
+
+	var i item
+	js, err := json.Marshal(merged)
+	if err != nil {
+		return item{}, nil, err
+	}
+	if err := json.Unmarshal(js, &i); err != nil {
+		return item{}, nil, err
+	}
+	return i, conflicts, nil
+}
+
+// fields returns the JSON encoding of each field of the item
+func fields(i item) (map[string]json.RawMessage, error) {
+	m := map[string]json.RawMessage{}
+	js, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(js, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// mergeTransitions returns the JSON encoding of the transitions of
+// base followed by the ones both sides added, in chronological order
+func mergeTransitions(base, ours, theirs item) (json.RawMessage, error) {
+	n := len(base.Transitions)
+	transitions := append(slices.Clone(ours.Transitions), theirs.Transitions[min(n, len(theirs.Transitions)):]...)
+	slices.SortStableFunc(transitions[min(n, len(ours.Transitions)):], func(a, b Transition) int {
+		return a.At.Compare(b.At)
+	})
+	return json.Marshal(transitions)
+}
+
+// earliest returns the earliest of two JSON encoded times
+func earliest(a, b json.RawMessage) json.RawMessage {
+	var ta, tb time.Time
+	if json.Unmarshal(a, &ta) != nil || ta.IsZero() {
+		return b
+	}
+	if json.Unmarshal(b, &tb) != nil || tb.IsZero() || ta.Before(tb) {
+		return a
+	}
+	return b
+}
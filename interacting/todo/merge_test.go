@@ -0,0 +1,140 @@
+package todo_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	base := todo.List{}
+	base.Add("Write report")
+	base.Add("Call vendor")
+	base.Add("Book flights")
+	base.Add("Water plants")
+	base.Add("Renew passport")
+
+	ours := clone(t, base)
+	theirs := clone(t, base)
+
+	// Both sides add an item, getting the same ID
+	ours.Add("Buy milk")
+	sub, err := theirs.AddSub(2, "Ask for a quote")
+	require.NoError(t, err)
+	require.Equal(t, 6, sub)
+	_, err = theirs.AddSub(sub, "Compare quotes")
+	require.NoError(t, err)
+
+	// Independent edits of the same item and a completion
+	require.NoError(t, ours.SetPriority(1, "A"))
+	require.NoError(t, theirs.AddTags(1, "work"))
+	require.NoError(t, theirs.Complete(1))
+
+	// Completed on both sides
+	require.NoError(t, theirs.Complete(3))
+	time.Sleep(time.Millisecond)
+	require.NoError(t, ours.Complete(3))
+
+	// Deleted on one side, completed on the other
+	require.NoError(t, ours.Delete(4))
+	require.NoError(t, theirs.Complete(4))
+
+	// Deleted on one side, edited on the other
+	require.NoError(t, theirs.Delete(5))
+	require.NoError(t, ours.SetPriority(5, "B"))
+
+	// Changed differently on both sides
+	require.NoError(t, ours.SetPriority(2, "A"))
+	require.NoError(t, theirs.SetPriority(2, "C"))
+
+	merged, conflicts, err := todo.Merge(base, ours, theirs)
+	require.NoError(t, err)
+
+	exp := "X 1: (A) Write report #work\n" +
+		"  2: (A) Call vendor [0/1]\n" +
+		"    8: Ask for a quote [0/1]\n" +
+		"      7: Compare quotes\n" +
+		"X 3: Book flights\n" +
+		"  5: (B) Renew passport\n" +
+		"  6: Buy milk\n"
+	assert.Equal(t, exp, merged.String())
+
+	i, err := merged.Index(3)
+	require.NoError(t, err)
+	j, err := theirs.Index(3)
+	require.NoError(t, err)
+	assert.True(t, theirs.Items[j].CompletedAt.Equal(merged.Items[i].CompletedAt), "expected the earliest completion")
+
+	require.Len(t, conflicts, 2)
+	assert.Equal(t, "2: Call vendor: both sides changed Priority", conflicts[0].String())
+	assert.Equal(t, "5: Renew passport: deleted by them but changed by us", conflicts[1].String())
+}
+
+func TestMerge_SameAddition(t *testing.T) {
+	base := todo.List{}
+	ours := clone(t, base)
+	ours.Add("Buy milk")
+	theirs := clone(t, ours)
+
+	merged, conflicts, err := todo.Merge(base, ours, theirs)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	assert.Len(t, merged.Items, 1)
+}
+
+func TestMerge_Dependencies(t *testing.T) {
+	base := todo.List{}
+	base.Add("Write report")
+	base.Add("Call vendor")
+	base.Add("Book flights")
+	ours := clone(t, base)
+	theirs := clone(t, base)
+
+	// Each side adds half of a cycle, and a dependency on an item
+	// the other side deletes
+	require.NoError(t, ours.AddDependency(1, 2))
+	require.NoError(t, theirs.AddDependency(2, 1))
+	require.NoError(t, ours.AddDependency(1, 3))
+	require.NoError(t, theirs.Delete(3))
+
+	merged, conflicts, err := todo.Merge(base, ours, theirs)
+	require.NoError(t, err)
+	assert.Equal(t, "  1: Write report [blocked by 2]\n  2: Call vendor\n", merged.String())
+	require.Len(t, conflicts, 2)
+	assert.Equal(t, "1: Write report: dropped the dependency on missing item 3", conflicts[0].String())
+	assert.Equal(t, "2: Call vendor: dropped the dependency on 1 closing a cycle", conflicts[1].String())
+}
+
+func TestMerge_Spawned(t *testing.T) {
+	base := todo.List{}
+	id := base.Add("Water plants")
+	r, err := todo.ParseRecurrence("after:3")
+	require.NoError(t, err)
+	require.NoError(t, base.SetRecurrence(id, r))
+	ours := clone(t, base)
+	theirs := clone(t, base)
+
+	ours.Add("Buy soil")
+	require.NoError(t, theirs.Complete(id))
+
+	merged, conflicts, err := todo.Merge(base, ours, theirs)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	require.Len(t, merged.Items, 3)
+	assert.Equal(t, "Water plants", merged.Items[2].Task)
+	assert.Equal(t, merged.Items[2].ID, merged.Items[0].Spawned,
+		"expected the spawned occurrence to follow its new ID")
+
+	require.NoError(t, merged.Reopen(id))
+	require.NoError(t, merged.Complete(id))
+	assert.Len(t, merged.Items, 3, "expected completing again to keep the merged occurrence")
+}
+
+func clone(t *testing.T, l todo.List) todo.List {
+	t.Helper()
+	c := todo.List{}
+	c.Sync(l)
+	return c
+}
@@ -0,0 +1,125 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidPriority = errors.New("priority must be a single letter from A to Z")
+)
+
+// Overdue reports whether the item is still open after its due date
+func (i item) Overdue(now time.Time) bool {
+	return !i.Done() && !i.Due.IsZero() && now.After(i.Due)
+}
+
+// SetDue sets the due date of the item with the given ID
+func (l *List) SetDue(id int, due time.Time) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	l.Items[i].Due = due
+	return nil
+}
+
+// ClearDue removes the due date of the item with the given ID
+func (l *List) ClearDue(id int) error {
+	return l.SetDue(id, time.Time{})
+}
+
+// SetPriority sets the priority of the item with the given ID.
+// Priorities are single letters where A is the most important
+func (l *List) SetPriority(id int, priority string) error {
+	p := strings.ToUpper(strings.TrimSpace(priority))
+	if len(p) != 1 || p[0] < 'A' || p[0] > 'Z' {
+		return fmt.Errorf("%w: %q", ErrInvalidPriority, priority)
+	}
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	l.Items[i].Priority = p
+	return nil
+}
+
+// ClearPriority removes the priority of the item with the given ID
+func (l *List) ClearPriority(id int) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	l.Items[i].Priority = ""
+	return nil
+}
+
+// AddTags adds tags to the item with the given ID.
+// Tags already set on the item are ignored
+func (l *List) AddTags(id int, tags ...string) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	ls := l.Items
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || slices.Contains(ls[i].Tags, tag) {
+			continue
+		}
+		ls[i].Tags = append(ls[i].Tags, tag)
+	}
+	slices.Sort(ls[i].Tags)
+	return nil
+}
+
+// RemoveTags removes tags from the item with the given ID
+func (l *List) RemoveTags(id int, tags ...string) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	ls := l.Items
+	ls[i].Tags = slices.DeleteFunc(ls[i].Tags, func(tag string) bool {
+		return slices.Contains(tags, tag)
+	})
+	return nil
+}
+
+// ClearTags removes all tags from the item with the given ID
+func (l *List) ClearTags(id int) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	l.Items[i].Tags = nil
+	return nil
+}
+
+// Sort orders the list by priority and then by due date.
+// Items without a priority or due date come after the ones that have it,
+// and items that tie keep their original order
+func (l *List) Sort() {
+	ls := l.Items
+	sort.SliceStable(ls, func(a, b int) bool {
+		pa, pb := ls[a].Priority, ls[b].Priority
+		if pa != pb {
+			if pa == "" || pb == "" {
+				return pb == ""
+			}
+			return pa < pb
+		}
+		da, db := ls[a].Due, ls[b].Due
+		if !da.Equal(db) {
+			if da.IsZero() || db.IsZero() {
+				return db.IsZero()
+			}
+			return da.Before(db)
+		}
+		return false
+	})
+}
@@ -0,0 +1,65 @@
+package todo_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+	"time"
+)
+
+func TestList_Priority(t *testing.T) {
+	l := todo.List{}
+	id := l.Add("New Task")
+	require.NoError(t, l.SetPriority(id, "b"))
+	assert.Equal(t, "B", l.Items[0].Priority, "expected priority %q, got %q", "B", l.Items[0].Priority)
+	err := l.SetPriority(id, "AB")
+	assert.ErrorIs(t, err, todo.ErrInvalidPriority)
+	require.NoError(t, l.ClearPriority(id))
+	assert.Empty(t, l.Items[0].Priority)
+	assert.ErrorIs(t, l.SetPriority(10, "A"), todo.ErrNotExists)
+}
+
+func TestList_Tags(t *testing.T) {
+	l := todo.List{}
+	id := l.Add("New Task")
+	require.NoError(t, l.AddTags(id, "work", "home", "work", " "))
+	assert.Equal(t, []string{"home", "work"}, l.Items[0].Tags)
+	require.NoError(t, l.RemoveTags(id, "home"))
+	assert.Equal(t, []string{"work"}, l.Items[0].Tags)
+	require.NoError(t, l.ClearTags(id))
+	assert.Empty(t, l.Items[0].Tags)
+}
+
+func TestList_Due(t *testing.T) {
+	l := todo.List{}
+	id := l.Add("New Task")
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, l.SetDue(id, now.Add(-time.Hour)))
+	assert.True(t, l.Items[0].Overdue(now), "expected item to be overdue")
+	require.NoError(t, l.Complete(id))
+	assert.False(t, l.Items[0].Overdue(now), "completed item should not be overdue")
+	require.NoError(t, l.ClearDue(id))
+	assert.True(t, l.Items[0].Due.IsZero())
+}
+
+func TestList_Sort(t *testing.T) {
+	l := todo.List{}
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	plain := l.Add("Plain")
+	late := l.Add("B later")
+	soon := l.Add("B sooner")
+	urgent := l.Add("A no due")
+	dueOnly := l.Add("Due only")
+	require.NoError(t, l.SetPriority(late, "B"))
+	require.NoError(t, l.SetDue(late, now.AddDate(0, 0, 2)))
+	require.NoError(t, l.SetPriority(soon, "B"))
+	require.NoError(t, l.SetDue(soon, now.AddDate(0, 0, 1)))
+	require.NoError(t, l.SetPriority(urgent, "A"))
+	require.NoError(t, l.SetDue(dueOnly, now))
+	l.Sort()
+	exp := []int{urgent, soon, late, dueOnly, plain}
+	for i, id := range exp {
+		assert.Equal(t, id, l.Items[i].ID, "expected item %d at position %d, got %d", id, i, l.Items[i].ID)
+	}
+}
@@ -0,0 +1,408 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrInvalidQuery = errors.New("invalid query")
+)
+
+// Query is a compiled filter expression, see ParseQuery
+type Query struct {
+	expr expr
+	now  time.Time
+}
+
+// expr is a node of the query syntax tree
+type expr interface {
+	match(i item, now time.Time) bool
+}
+
+type (
+	andExpr  struct{ left, right expr }
+	orExpr   struct{ left, right expr }
+	notExpr  struct{ expr expr }
+	funcExpr func(i item, now time.Time) bool
+)
+
+func (e andExpr) match(i item, now time.Time) bool {
+	return e.left.match(i, now) && e.right.match(i, now)
+}
+
+func (e orExpr) match(i item, now time.Time) bool {
+	return e.left.match(i, now) || e.right.match(i, now)
+}
+
+func (e notExpr) match(i item, now time.Time) bool {
+	return !e.expr.match(i, now)
+}
+
+func (f funcExpr) match(i item, now time.Time) bool {
+	return f(i, now)
+}
+
+// ParseQuery compiles a filter expression. Relative dates in the
+// expression are resolved against now.
+//
+// An expression combines predicates with and, or, not and parentheses.
+// Predicates next to each other are joined with and. The predicates are:
+//
+//	done, undone (or open), overdue
+//	word or "quoted text"   task contains the text, ignoring case
+//	text:word               same as above
+//	text~"regexp"           task matches the regular expression
+//	created<date            also completed and due, with the
+//	                        operators <, <=, >, >=, = and :
+//	tag:name                item has the tag
+//	list:name               item belongs to the list
+//	status:blocked          item has the status
+//	priority:A              also with <, <=, >, >=, where A < B
+//	id=3                    also with <, <=, >, >=
+//
+// Dates are expressions accepted by ParseDate, such as 2024-01-31,
+// yesterday, -7d, "this week" or "2 weeks ago", quoted when they
+// have spaces.
+// Dates match the whole day, so created<=2024-01-31 includes items
+// created on that day
+func ParseQuery(s string, now time.Time) (*Query, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, now: now}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidQuery, p.peek().text)
+	}
+	return &Query{expr: e, now: now}, nil
+}
+
+// Match reports whether the item satisfies the query
+func (q *Query) Match(i item) bool {
+	return q.expr.match(i, q.now)
+}
+
+// Select returns the items of the list matching the query
+func (l *List) Select(q *Query) List {
+	selected := List{from: l.whole()}
+	for _, value := range l.Items {
+		if q.Match(value) {
+			selected.Items = append(selected.Items, value)
+		}
+	}
+	return selected
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+const opChars = ":~=<>"
+
+// lex splits the query into words, quoted strings, operators and parentheses
+func lex(s string) ([]token, error) {
+	var tokens []token
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")"})
+			i++
+		case r == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(rs) && rs[i] != '"'; i++ {
+				if rs[i] == '\\' && i+1 < len(rs) {
+					i++
+				}
+				b.WriteRune(rs[i])
+			}
+			if i == len(rs) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalidQuery)
+			}
+			i++
+			tokens = append(tokens, token{tokString, b.String()})
+		case strings.ContainsRune(opChars, r):
+			j := i + 1
+			for j < len(rs) && strings.ContainsRune(opChars, rs[j]) {
+				j++
+			}
+			tokens = append(tokens, token{tokOp, string(rs[i:j])})
+			i = j
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !strings.ContainsRune(`()"`+opChars, rs[j]) {
+				j++
+			}
+			tokens = append(tokens, token{tokWord, string(rs[i:j])})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser over the query tokens
+type parser struct {
+	tokens []token
+	pos    int
+	now    time.Time
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// isKeyword reports whether the next token is the given keyword
+func (p *parser) isKeyword(k string) bool {
+	t := p.peek()
+	return !p.done() && t.kind == tokWord && strings.EqualFold(t.text, k)
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for !p.done() && p.peek().kind != tokRParen && !p.isKeyword("or") {
+		if p.isKeyword("and") {
+			p.next()
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.done() {
+		return nil, fmt.Errorf("%w: unexpected end of query", ErrInvalidQuery)
+	}
+	if p.isKeyword("not") {
+		p.next()
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	}
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRParen {
+			return nil, fmt.Errorf("%w: missing )", ErrInvalidQuery)
+		}
+		return e, nil
+	case tokString:
+		return textContains(t.text), nil
+	case tokWord:
+		if !p.done() && p.peek().kind == tokOp {
+			op := p.next().text
+			if p.done() {
+				return nil, fmt.Errorf("%w: missing value after %s%s", ErrInvalidQuery, t.text, op)
+			}
+			value := p.next()
+			if value.kind != tokWord && value.kind != tokString {
+				return nil, fmt.Errorf("%w: missing value after %s%s", ErrInvalidQuery, t.text, op)
+			}
+			return p.predicate(strings.ToLower(t.text), op, value.text)
+		}
+		switch strings.ToLower(t.text) {
+		case "done":
+			return funcExpr(func(i item, _ time.Time) bool { return i.Done() }), nil
+		case "undone", "open":
+			return funcExpr(func(i item, _ time.Time) bool { return !i.Done() }), nil
+		case "overdue":
+			return funcExpr(func(i item, now time.Time) bool { return i.Overdue(now) }), nil
+		}
+		return textContains(t.text), nil
+	}
+	return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidQuery, t.text)
+}
+
+// predicate builds the expression for field op value
+func (p *parser) predicate(field, op, value string) (expr, error) {
+	switch field {
+	case "text", "task":
+		switch op {
+		case ":", "=":
+			return textContains(value), nil
+		case "~":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidQuery, err)
+			}
+			return funcExpr(func(i item, _ time.Time) bool { return re.MatchString(i.Task) }), nil
+		}
+	case "created", "completed", "due":
+		day, err := p.parseDay(value)
+		if err != nil {
+			return nil, err
+		}
+		get := func(i item) time.Time {
+			switch field {
+			case "created":
+				return i.CreatedAt
+			case "completed":
+				return i.CompletedAt
+			}
+			return i.Due
+		}
+		cmp, err := compareDay(op, day)
+		if err != nil {
+			return nil, err
+		}
+		return funcExpr(func(i item, _ time.Time) bool {
+			t := get(i)
+			return !t.IsZero() && cmp(t)
+		}), nil
+	case "tag":
+		if op == ":" || op == "=" {
+			return funcExpr(func(i item, _ time.Time) bool { return slices.Contains(i.Tags, value) }), nil
+		}
+	case "status":
+		if op == ":" || op == "=" {
+			return funcExpr(func(i item, _ time.Time) bool { return i.Status == strings.ToLower(value) }), nil
+		}
+	case "list":
+		if op == ":" || op == "=" {
+			return funcExpr(func(i item, _ time.Time) bool { return i.ListName() == value }), nil
+		}
+	case "priority", "pri":
+		v := strings.ToUpper(value)
+		cmp, err := compareOrdered(op, v)
+		if err != nil {
+			return nil, err
+		}
+		return funcExpr(func(i item, _ time.Time) bool { return i.Priority != "" && cmp(i.Priority) }), nil
+	case "id":
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid id %q", ErrInvalidQuery, value)
+		}
+		cmp, err := compareOrdered(op, v)
+		if err != nil {
+			return nil, err
+		}
+		return funcExpr(func(i item, _ time.Time) bool { return cmp(i.ID) }), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidQuery, field)
+	}
+	return nil, fmt.Errorf("%w: operator %q not supported for %s", ErrInvalidQuery, op, field)
+}
+
+// parseDay resolves a date value to the start of that day in local time
+func (p *parser) parseDay(value string) (time.Time, error) {
+	t, _, err := ParseDate(value, p.now.Local())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %w", ErrInvalidQuery, err)
+	}
+	return startOfDay(t), nil
+}
+
+// compareDay returns a function comparing a time against the whole day
+// starting at day
+func compareDay(op string, day time.Time) (func(time.Time) bool, error) {
+	end := day.AddDate(0, 0, 1)
+	switch op {
+	case "<":
+		return func(t time.Time) bool { return t.Before(day) }, nil
+	case "<=":
+		return func(t time.Time) bool { return t.Before(end) }, nil
+	case ">":
+		return func(t time.Time) bool { return !t.Before(end) }, nil
+	case ">=":
+		return func(t time.Time) bool { return !t.Before(day) }, nil
+	case "=", ":":
+		return func(t time.Time) bool { return !t.Before(day) && t.Before(end) }, nil
+	}
+	return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidQuery, op)
+}
+
+// compareOrdered returns a function comparing a value against v
+func compareOrdered[T int | string](op string, v T) (func(T) bool, error) {
+	switch op {
+	case "<":
+		return func(x T) bool { return x < v }, nil
+	case "<=":
+		return func(x T) bool { return x <= v }, nil
+	case ">":
+		return func(x T) bool { return x > v }, nil
+	case ">=":
+		return func(x T) bool { return x >= v }, nil
+	case "=", ":":
+		return func(x T) bool { return x == v }, nil
+	}
+	return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidQuery, op)
+}
+
+// textContains matches items whose task contains s, ignoring case
+func textContains(s string) expr {
+	s = strings.ToLower(s)
+	return funcExpr(func(i item, _ time.Time) bool {
+		return strings.Contains(strings.ToLower(i.Task), s)
+	})
+}
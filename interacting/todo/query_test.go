@@ -0,0 +1,92 @@
+package todo_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pragprog.com/rggo/interacting/todo"
+	"strings"
+	"testing"
+	"time"
+)
+
+func queryList(t *testing.T) todo.List {
+	t.Helper()
+	input := `(A) 2024-01-15 Deploy service +work due:2024-01-16
+x 2024-01-16 2024-01-08 Deploy database +work
+(C) 2024-01-16 Water plants +home list:house
+2024-01-02 Call about deployment due:2024-01-10
+`
+	l, err := todo.ParseTodoTxt(strings.NewReader(input))
+	require.NoError(t, err)
+	imported := todo.List{}
+	imported.Import(l)
+	return imported
+}
+
+func TestParseQuery(t *testing.T) {
+	now := time.Date(2024, 1, 17, 10, 0, 0, 0, time.Local)
+	l := queryList(t)
+	testCases := []struct {
+		name   string
+		query  string
+		expIDs []int
+	}{
+		{name: "Text", query: "deploy", expIDs: []int{1, 2, 4}},
+		{name: "QuotedText", query: `"water plants"`, expIDs: []int{3}},
+		{name: "Regexp", query: `text~"^Deploy (s|d)"`, expIDs: []int{1, 2}},
+		{name: "Undone", query: "undone deploy", expIDs: []int{1, 4}},
+		{name: "Done", query: "done", expIDs: []int{2}},
+		{name: "CreatedThisWeek", query: "open and created>=-7d and text:deploy", expIDs: []int{1}},
+		{name: "CreatedThisWeekRelative", query: `open and created>="this week" and deploy`, expIDs: []int{1}},
+		{name: "CreatedThisMonth", query: `created>="this month" and created<"this week"`, expIDs: []int{2, 4}},
+		{name: "List", query: "list:house or list:default and done", expIDs: []int{2, 3}},
+		{name: "CreatedOnDay", query: "created=2024-01-16", expIDs: []int{3}},
+		{name: "CreatedBefore", query: "created<2024-01-08", expIDs: []int{4}},
+		{name: "CreatedUpTo", query: "created<=2024-01-08", expIDs: []int{2, 4}},
+		{name: "CompletedAfter", query: "completed>=yesterday", expIDs: []int{2}},
+		{name: "NaturalDate", query: `created>="last monday"`, expIDs: []int{1, 3}},
+		{name: "DaysAgo", query: `due<"6 days ago"`, expIDs: []int{4}},
+		{name: "Overdue", query: "overdue", expIDs: []int{1, 4}},
+		{name: "DueBefore", query: "due<today", expIDs: []int{1, 4}},
+		{name: "Tag", query: "tag:work", expIDs: []int{1, 2}},
+		{name: "Priority", query: "priority<=b", expIDs: []int{1}},
+		{name: "ID", query: "id>2", expIDs: []int{3, 4}},
+		{name: "Or", query: "tag:home or done", expIDs: []int{2, 3}},
+		{name: "Not", query: "not tag:work", expIDs: []int{3, 4}},
+		{name: "Parens", query: "not (tag:work or tag:home) and open", expIDs: []int{4}},
+		{name: "Precedence", query: "tag:home or tag:work and done", expIDs: []int{2, 3}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := todo.ParseQuery(tc.query, now)
+			require.NoError(t, err)
+			var ids []int
+			for _, value := range l.Select(q).Items {
+				ids = append(ids, value.ID)
+			}
+			assert.Equal(t, tc.expIDs, ids)
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	now := time.Now()
+	for _, query := range []string{
+		"",
+		"(done",
+		"done)",
+		`text~"("`,
+		`"unterminated`,
+		"created>someday",
+		"color:red",
+		"tag<work",
+		"id=one",
+		"not",
+		"priority:",
+	} {
+		t.Run(query, func(t *testing.T) {
+			_, err := todo.ParseQuery(query, now)
+			assert.ErrorIs(t, err, todo.ErrInvalidQuery)
+		})
+	}
+}
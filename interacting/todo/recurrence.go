@@ -0,0 +1,224 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidRecurrence = errors.New("invalid recurrence")
+)
+
+// Recurrence kinds
+const (
+	RecurDaily   = "daily"
+	RecurWeekly  = "weekly"
+	RecurMonthly = "monthly"
+	RecurAfter   = "after"
+)
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Recurrence describes how a task repeats.
+// Weekdays applies to weekly rules, Day to monthly rules and
+// Days to rules repeating a number of days after completion
+type Recurrence struct {
+	Kind     string
+	Weekdays []time.Weekday `json:",omitempty"`
+	Day      int            `json:",omitempty"`
+	Days     int            `json:",omitempty"`
+}
+
+// ParseRecurrence parses a recurrence rule in one of the forms
+// daily, weekly, weekly:mon,thu, monthly, monthly:15 or after:3
+func ParseRecurrence(s string) (*Recurrence, error) {
+	kind, arg, hasArg := strings.Cut(strings.ToLower(strings.TrimSpace(s)), ":")
+	r := &Recurrence{Kind: kind}
+	switch {
+	case kind == RecurDaily && !hasArg:
+		return r, nil
+	case kind == RecurWeekly:
+		if !hasArg {
+			return r, nil
+		}
+		for _, name := range strings.Split(arg, ",") {
+			d := slices.Index(weekdays, strings.TrimSpace(name))
+			if d < 0 {
+				return nil, fmt.Errorf("%w: unknown weekday %q", ErrInvalidRecurrence, name)
+			}
+			if !slices.Contains(r.Weekdays, time.Weekday(d)) {
+				r.Weekdays = append(r.Weekdays, time.Weekday(d))
+			}
+		}
+		slices.Sort(r.Weekdays)
+		return r, nil
+	case kind == RecurMonthly:
+		if !hasArg {
+			return r, nil
+		}
+		day, err := strconv.Atoi(arg)
+		if err != nil || day < 1 || day > 31 {
+			return nil, fmt.Errorf("%w: day of month must be between 1 and 31, got %q", ErrInvalidRecurrence, arg)
+		}
+		r.Day = day
+		return r, nil
+	case kind == RecurAfter && hasArg:
+		days, err := strconv.Atoi(arg)
+		if err != nil || days < 1 {
+			return nil, fmt.Errorf("%w: number of days must be positive, got %q", ErrInvalidRecurrence, arg)
+		}
+		r.Days = days
+		return r, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrInvalidRecurrence, s)
+}
+
+// validate checks a rule that was not parsed by ParseRecurrence,
+// such as one decoded from JSON, against the same rules
+func (r *Recurrence) validate() error {
+	valid := false
+	switch r.Kind {
+	case RecurDaily:
+		valid = len(r.Weekdays) == 0 && r.Day == 0 && r.Days == 0
+	case RecurWeekly:
+		valid = r.Day == 0 && r.Days == 0 && !slices.ContainsFunc(r.Weekdays, func(d time.Weekday) bool {
+			return d < time.Sunday || d > time.Saturday
+		})
+	case RecurMonthly:
+		valid = len(r.Weekdays) == 0 && r.Day >= 0 && r.Day <= 31 && r.Days == 0
+	case RecurAfter:
+		valid = len(r.Weekdays) == 0 && r.Day == 0 && r.Days >= 1
+	}
+	if !valid {
+		return fmt.Errorf("%w: %+v", ErrInvalidRecurrence, *r)
+	}
+	return nil
+}
+
+// String formats the rule in the syntax accepted by ParseRecurrence
+func (r *Recurrence) String() string {
+	switch {
+	case r.Kind == RecurWeekly && len(r.Weekdays) > 0:
+		var names []string
+		for _, d := range r.Weekdays {
+			names = append(names, weekdays[d])
+		}
+		return r.Kind + ":" + strings.Join(names, ",")
+	case r.Kind == RecurMonthly && r.Day > 0:
+		return fmt.Sprintf("%s:%d", r.Kind, r.Day)
+	case r.Kind == RecurAfter:
+		return fmt.Sprintf("%s:%d", r.Kind, r.Days)
+	}
+	return r.Kind
+}
+
+// Next returns the due date of the occurrence following one due at due
+// and completed at completed.
+// Scheduled rules keep moving forward from due until they pass the
+// completion time, so late completions do not spawn overdue occurrences.
+// A zero due date schedules from the completion time
+func (r *Recurrence) Next(due, completed time.Time) time.Time {
+	if r.Kind == RecurAfter {
+		return completed.AddDate(0, 0, r.Days)
+	}
+	next := due
+	if next.IsZero() {
+		next = completed
+	}
+	for {
+		next = r.step(next)
+		if next.After(completed) {
+			return next
+		}
+	}
+}
+
+// step returns the first scheduled date after t
+func (r *Recurrence) step(t time.Time) time.Time {
+	switch r.Kind {
+	case RecurWeekly:
+		if len(r.Weekdays) == 0 {
+			return t.AddDate(0, 0, 7)
+		}
+		for i := 1; i <= 7; i++ {
+			next := t.AddDate(0, 0, i)
+			if slices.Contains(r.Weekdays, next.Weekday()) {
+				return next
+			}
+		}
+	case RecurMonthly:
+		day := r.Day
+		if day == 0 {
+			day = t.Day()
+		}
+		for i := 0; i <= 1; i++ {
+			// Start from the first of the month so AddDate never overflows
+			first := time.Date(t.Year(), t.Month()+time.Month(i), 1,
+				t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+			last := first.AddDate(0, 1, -1).Day()
+			next := first.AddDate(0, 0, min(day, last)-1)
+			if next.After(t) {
+				return next
+			}
+		}
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// anchor returns a monthly rule without a day as a copy repeating on
+// the day of t, so occurrences clamped to the end of a short month
+// go back to that day afterwards. Other rules are returned as they are
+func (r *Recurrence) anchor(t time.Time) *Recurrence {
+	if r == nil || r.Kind != RecurMonthly || r.Day != 0 || t.IsZero() {
+		return r
+	}
+	anchored := *r
+	anchored.Day = t.Day()
+	return &anchored
+}
+
+// SetRecurrence makes the item with the given ID repeat following r.
+// Monthly rules without a day repeat on the day of the due date
+func (l *List) SetRecurrence(id int, r *Recurrence) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	l.Items[i].Recur = r.anchor(l.Items[i].Due)
+	return nil
+}
+
+// ClearRecurrence stops the item with the given ID from repeating
+func (l *List) ClearRecurrence(id int) error {
+	return l.SetRecurrence(id, nil)
+}
+
+// spawn appends the occurrence following the recurring item at
+// position i, completed at completed, and returns its ID
+func (l *List) spawn(i int, completed time.Time) int {
+	current := l.Items[i]
+	// Rules set before being anchored take the day they start from
+	from := current.Due
+	if from.IsZero() {
+		from = completed
+	}
+	current.Recur = current.Recur.anchor(from)
+	next := item{
+		ID:        l.newID(),
+		Task:      current.Task,
+		Status:    StatusTodo,
+		CreatedAt: completed,
+		Due:       current.Recur.Next(current.Due, completed),
+		Priority:  current.Priority,
+		Tags:      slices.Clone(current.Tags),
+		Recur:     current.Recur,
+		Parent:    current.Parent,
+		List:      current.List,
+	}
+	l.Items = append(l.Items, next)
+	return next.ID
+}
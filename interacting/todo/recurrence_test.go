@@ -0,0 +1,121 @@
+package todo_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	testCases := []struct {
+		name   string
+		rule   string
+		exp    string
+		expErr error
+	}{
+		{name: "Daily", rule: "daily", exp: "daily"},
+		{name: "Weekly", rule: "weekly", exp: "weekly"},
+		{name: "WeeklyDays", rule: "Weekly:thu,mon,thu", exp: "weekly:mon,thu"},
+		{name: "Monthly", rule: "monthly:15", exp: "monthly:15"},
+		{name: "After", rule: "after:3", exp: "after:3"},
+		{name: "InvalidWeekday", rule: "weekly:monday", expErr: todo.ErrInvalidRecurrence},
+		{name: "InvalidDay", rule: "monthly:32", expErr: todo.ErrInvalidRecurrence},
+		{name: "MissingDays", rule: "after", expErr: todo.ErrInvalidRecurrence},
+		{name: "Unknown", rule: "yearly", expErr: todo.ErrInvalidRecurrence},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := todo.ParseRecurrence(tc.rule)
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, r.String())
+		})
+	}
+}
+
+func TestRecurrence_Next(t *testing.T) {
+	// Wednesday
+	due := time.Date(2024, 1, 31, 18, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name      string
+		rule      string
+		due       time.Time
+		completed time.Time
+		exp       time.Time
+	}{
+		{name: "Daily", rule: "daily", due: due, completed: due.Add(-time.Hour),
+			exp: time.Date(2024, 2, 1, 18, 0, 0, 0, time.UTC)},
+		{name: "DailyLate", rule: "daily", due: due, completed: due.AddDate(0, 0, 3),
+			exp: time.Date(2024, 2, 4, 18, 0, 0, 0, time.UTC)},
+		{name: "Weekly", rule: "weekly", due: due, completed: due,
+			exp: time.Date(2024, 2, 7, 18, 0, 0, 0, time.UTC)},
+		{name: "WeeklyDays", rule: "weekly:mon,thu", due: due, completed: due,
+			exp: time.Date(2024, 2, 1, 18, 0, 0, 0, time.UTC)},
+		{name: "MonthlyClamped", rule: "monthly:31", due: due, completed: due,
+			exp: time.Date(2024, 2, 29, 18, 0, 0, 0, time.UTC)},
+		{name: "MonthlySameMonth", rule: "monthly:15", due: time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC),
+			completed: time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC),
+			exp:       time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{name: "After", rule: "after:3", due: due, completed: due.AddDate(0, 0, 1),
+			exp: time.Date(2024, 2, 4, 18, 0, 0, 0, time.UTC)},
+		{name: "NoDue", rule: "daily", completed: due,
+			exp: time.Date(2024, 2, 1, 18, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := todo.ParseRecurrence(tc.rule)
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, r.Next(tc.due, tc.completed))
+		})
+	}
+}
+
+func TestList_SetRecurrenceMonthly(t *testing.T) {
+	l := todo.List{}
+	id := l.Add("Pay rent")
+	due := time.Date(2024, 1, 31, 18, 0, 0, 0, time.UTC)
+	require.NoError(t, l.SetDue(id, due))
+	r, err := todo.ParseRecurrence("monthly")
+	require.NoError(t, err)
+	require.NoError(t, l.SetRecurrence(id, r))
+	assert.Equal(t, "monthly:31", l.Items[0].Recur.String(), "expected the rule to keep the day of the due date")
+	assert.Equal(t, "monthly", r.String(), "expected the parsed rule to be left untouched")
+
+	feb := l.Items[0].Recur.Next(due, due)
+	assert.Equal(t, time.Date(2024, 2, 29, 18, 0, 0, 0, time.UTC), feb)
+	assert.Equal(t, time.Date(2024, 3, 31, 18, 0, 0, 0, time.UTC), l.Items[0].Recur.Next(feb, feb),
+		"expected the day of the due date after the end of February")
+}
+
+func TestList_CompleteRecurring(t *testing.T) {
+	l := todo.List{}
+	id := l.Add("Rotate on-call log")
+	r, err := todo.ParseRecurrence("after:7")
+	require.NoError(t, err)
+	require.NoError(t, l.SetRecurrence(id, r))
+	require.NoError(t, l.SetPriority(id, "B"))
+	require.NoError(t, l.AddTags(id, "ops"))
+
+	require.NoError(t, l.Complete(id))
+	require.Len(t, l.Items, 2, "expected next occurrence to be added")
+	assert.True(t, l.Items[0].Done())
+	next := l.Items[1]
+	assert.Equal(t, 2, next.ID)
+	assert.Equal(t, "Rotate on-call log", next.Task)
+	assert.False(t, next.Done())
+	assert.Equal(t, "B", next.Priority)
+	assert.Equal(t, []string{"ops"}, next.Tags)
+	assert.Equal(t, l.Items[0].CompletedAt.AddDate(0, 0, 7), next.Due)
+
+	require.NoError(t, l.Complete(id))
+	assert.Len(t, l.Items, 2, "completing a done item again should not add occurrences")
+
+	require.NoError(t, l.ClearRecurrence(next.ID))
+	require.NoError(t, l.Complete(next.ID))
+	assert.Len(t, l.Items, 2)
+}
@@ -0,0 +1,156 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	ErrInvalidPeriod = errors.New("invalid report period")
+)
+
+// Report periods
+const (
+	PeriodDay  = "day"
+	PeriodWeek = "week"
+)
+
+// Report summarizes the completion history of a list
+type Report struct {
+	// Period is PeriodDay or PeriodWeek
+	Period string
+	// Buckets count the items completed in each of the last periods,
+	// oldest first
+	Buckets []Bucket
+	// Completed counts the items completed over all buckets
+	Completed int
+	// Open counts the items still open
+	Open int
+	// AverageLeadDays is the average time from creation to completion
+	// of the items completed over all buckets, in days
+	AverageLeadDays float64
+	// Trend is the change in completed items from one period to the
+	// next, fitted over the buckets
+	Trend float64
+	// Oldest lists the oldest open items, oldest first
+	Oldest []Aged
+}
+
+// Bucket counts the items completed in the period starting at Start
+type Bucket struct {
+	Start     time.Time
+	Completed int
+}
+
+// Aged is an open item along with its age in days
+type Aged struct {
+	ID      int
+	Task    string
+	AgeDays float64
+}
+
+// Report analyzes the completion history of the list over the last n
+// periods up to now, listing at most oldest of the open items.
+// Weeks start on Monday
+func (l *List) Report(period string, n, oldest int, now time.Time) (Report, error) {
+	if n < 1 {
+		return Report{}, fmt.Errorf("%w: number of periods must be positive, got %d", ErrInvalidPeriod, n)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	step := func(t time.Time, k int) time.Time { return t.AddDate(0, 0, k) }
+	switch period {
+	case PeriodDay:
+	case PeriodWeek:
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		step = func(t time.Time, k int) time.Time { return t.AddDate(0, 0, 7*k) }
+	default:
+		return Report{}, fmt.Errorf("%w: %q: use %s or %s", ErrInvalidPeriod, period, PeriodDay, PeriodWeek)
+	}
+
+	r := Report{Period: period, Buckets: make([]Bucket, n)}
+	for i := range r.Buckets {
+		r.Buckets[i].Start = step(start, i-n+1)
+	}
+	var lead time.Duration
+	var open []item
+	for _, value := range l.Items {
+		if !value.Done() {
+			r.Open++
+			open = append(open, value)
+			continue
+		}
+		i := n - 1
+		for i >= 0 && value.CompletedAt.Before(r.Buckets[i].Start) {
+			i--
+		}
+		if i < 0 {
+			continue
+		}
+		r.Buckets[i].Completed++
+		r.Completed++
+		lead += value.CompletedAt.Sub(value.CreatedAt)
+	}
+	if r.Completed > 0 {
+		r.AverageLeadDays = days(lead) / float64(r.Completed)
+	}
+	r.Trend = trend(r.Buckets)
+
+	slices.SortStableFunc(open, func(a, b item) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	for _, value := range open[:max(0, min(oldest, len(open)))] {
+		r.Oldest = append(r.Oldest, Aged{value.ID, value.Task, days(now.Sub(value.CreatedAt))})
+	}
+	return r, nil
+}
+
+// String formats the report as tables
+func (r Report) String() string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tCOMPLETED\n", strings.ToUpper(r.Period))
+	for _, bucket := range r.Buckets {
+		fmt.Fprintf(tw, "%s\t%d\n", bucket.Start.Format(time.DateOnly), bucket.Completed)
+	}
+	tw.Flush()
+	fmt.Fprintf(&b, "\nCompleted: %d (trend %+.1f per %s)\n", r.Completed, r.Trend, r.Period)
+	fmt.Fprintf(&b, "Average lead time: %.1f days\n", r.AverageLeadDays)
+	fmt.Fprintf(&b, "Open: %d\n", r.Open)
+	if len(r.Oldest) == 0 {
+		return b.String()
+	}
+	b.WriteString("\nOLDEST OPEN\n")
+	tw = tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, a := range r.Oldest {
+		fmt.Fprintf(tw, "%d: %s\t%.1f days\n", a.ID, a.Task, a.AgeDays)
+	}
+	tw.Flush()
+	return b.String()
+}
+
+// days converts a duration to days
+func days(d time.Duration) float64 {
+	return d.Hours() / 24
+}
+
+// trend returns the slope of the least squares line
+// fitted to the completion counts of the buckets
+func trend(buckets []Bucket) float64 {
+	n := float64(len(buckets))
+	if n < 2 {
+		return 0
+	}
+	var sx, sy, sxy, sxx float64
+	for i, bucket := range buckets {
+		x, y := float64(i), float64(bucket.Completed)
+		sx += x
+		sy += y
+		sxy += x * y
+		sxx += x * x
+	}
+	return (n*sxy - sx*sy) / (n*sxx - sx*sx)
+}
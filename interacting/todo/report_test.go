@@ -0,0 +1,63 @@
+package todo_test
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+	"time"
+)
+
+func TestList_Report(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	js := `[
+		{"ID":1,"Task":"Old","Status":"done","CreatedAt":"2026-09-01T09:00:00Z","CompletedAt":"2026-09-02T09:00:00Z"},
+		{"ID":2,"Task":"Last week","Status":"done","CreatedAt":"2026-10-05T09:00:00Z","CompletedAt":"2026-10-07T09:00:00Z"},
+		{"ID":3,"Task":"Monday","Status":"done","CreatedAt":"2026-10-11T09:00:00Z","CompletedAt":"2026-10-12T09:00:00Z"},
+		{"ID":4,"Task":"Today","Status":"done","CreatedAt":"2026-10-14T09:00:00Z","CompletedAt":"2026-10-14T09:00:00Z"},
+		{"ID":5,"Task":"Newer","Status":"todo","CreatedAt":"2026-10-10T12:00:00Z"},
+		{"ID":6,"Task":"Oldest","Status":"blocked","CreatedAt":"2026-10-04T12:00:00Z"}
+	]`
+	l := todo.List{}
+	require.NoError(t, json.Unmarshal([]byte(js), &l))
+
+	t.Run("Week", func(t *testing.T) {
+		r, err := l.Report(todo.PeriodWeek, 3, 1, now)
+		require.NoError(t, err)
+		require.Len(t, r.Buckets, 3)
+		assert.Equal(t, time.Date(2026, 9, 28, 0, 0, 0, 0, time.UTC), r.Buckets[0].Start)
+		assert.Equal(t, []int{0, 1, 2}, []int{r.Buckets[0].Completed, r.Buckets[1].Completed, r.Buckets[2].Completed})
+		assert.Equal(t, 3, r.Completed, "expected completions before the first bucket to be left out")
+		assert.Equal(t, 2, r.Open)
+		assert.InDelta(t, 1.0, r.AverageLeadDays, 0.001)
+		assert.InDelta(t, 1.0, r.Trend, 0.001)
+		assert.Equal(t, []todo.Aged{{ID: 6, Task: "Oldest", AgeDays: 10}}, r.Oldest)
+
+		exp := "WEEK        COMPLETED\n" +
+			"2026-09-28  0\n" +
+			"2026-10-05  1\n" +
+			"2026-10-12  2\n" +
+			"\nCompleted: 3 (trend +1.0 per week)\n" +
+			"Average lead time: 1.0 days\n" +
+			"Open: 2\n" +
+			"\nOLDEST OPEN\n" +
+			"6: Oldest  10.0 days\n"
+		assert.Equal(t, exp, r.String())
+	})
+	t.Run("Day", func(t *testing.T) {
+		r, err := l.Report(todo.PeriodDay, 3, 0, now)
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), r.Buckets[0].Start)
+		assert.Equal(t, []int{1, 0, 1}, []int{r.Buckets[0].Completed, r.Buckets[1].Completed, r.Buckets[2].Completed})
+		assert.InDelta(t, 0.5, r.AverageLeadDays, 0.001)
+		assert.Empty(t, r.Oldest)
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := l.Report("month", 3, 0, now)
+		assert.ErrorIs(t, err, todo.ErrInvalidPeriod)
+		_, err = l.Report(todo.PeriodDay, 0, 0, now)
+		assert.ErrorIs(t, err, todo.ErrInvalidPeriod)
+	})
+}
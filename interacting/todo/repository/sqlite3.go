@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"pragprog.com/rggo/interacting/todo"
+	"slices"
+	"sync"
+	"time"
+
+	// Blank import for sqlite3 driver only
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	createTableTodo string = `CREATE TABLE IF NOT EXISTS "todo" (
+"id" INTEGER,
+"position" INTEGER NOT NULL,
+"data" TEXT NOT NULL,
+PRIMARY KEY("id")
+);`
+	// meta keeps the highest ID ever assigned, so the IDs
+	// of deleted items are not handed out again
+	createTableMeta string = `CREATE TABLE IF NOT EXISTS "meta" (
+"key" TEXT,
+"value" INTEGER NOT NULL,
+PRIMARY KEY("key")
+);`
+)
+
+// dbRepo stores one row per item, so an update only writes
+// the items that changed instead of the whole list
+type dbRepo struct {
+	db *sql.DB
+	sync.Mutex
+}
+
+func NewSQLite3Repo(dbfile string) (*dbRepo, error) {
+	// Immediate transactions take the write lock up front so concurrent
+	// processes wait for each other instead of failing on upgrade
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_txlock=immediate&_busy_timeout=5000", dbfile))
+	if err != nil {
+		return nil, err
+	}
+	db.SetConnMaxLifetime(30 * time.Minute)
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(createTableTodo); err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(createTableMeta); err != nil {
+		return nil, err
+	}
+
+	return &dbRepo{
+		db: db,
+	}, nil
+}
+
+func (r *dbRepo) Load(l *todo.List) error {
+	r.Lock()
+	defer r.Unlock()
+	return load(r.db, l)
+}
+
+func (r *dbRepo) Update(fn func(*todo.List) error) error {
+	r.Lock()
+	defer r.Unlock()
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	l := todo.List{}
+	if err := load(tx, &l); err != nil {
+		return err
+	}
+	last := l.LastID
+	// Snapshot the stored rows to find out what fn changes
+	before, err := snapshot(tx)
+	if err != nil {
+		return err
+	}
+	if err := fn(&l); err != nil {
+		return err
+	}
+	if err := save(tx, l, before); err != nil {
+		return err
+	}
+	if err := saveLastID(tx, l.LastID, last); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *dbRepo) Close() error {
+	return r.db.Close()
+}
+
+// row is the stored representation of an item
+type row struct {
+	position int
+	data     string
+}
+
+// snapshot returns the stored rows indexed by item ID
+func snapshot(tx *sql.Tx) (map[int]row, error) {
+	rows, err := tx.Query("SELECT id, position, data FROM todo")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	before := map[int]row{}
+	for rows.Next() {
+		var (
+			id int
+			r  row
+		)
+		if err := rows.Scan(&id, &r.position, &r.data); err != nil {
+			return nil, err
+		}
+		before[id] = r
+	}
+	return before, rows.Err()
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func load(q querier, l *todo.List) error {
+	rows, err := q.Query("SELECT data FROM todo ORDER BY position")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var data [][]byte
+	for rows.Next() {
+		var d []byte
+		if err := rows.Scan(&d); err != nil {
+			return err
+		}
+		data = append(data, d)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	*l = todo.List{}
+	l.Items = slices.Grow(l.Items, len(data))[:len(data)]
+	for i, d := range data {
+		if err := json.Unmarshal(d, &l.Items[i]); err != nil {
+			return fmt.Errorf("unable to decode item: %w", err)
+		}
+	}
+	err = q.QueryRow(`SELECT value FROM meta WHERE key="last_id"`).Scan(&l.LastID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+// saveLastID stores the last ID assigned when it changed from before
+func saveLastID(tx *sql.Tx, id, before int) error {
+	if id == before {
+		return nil
+	}
+	_, err := tx.Exec(
+		`INSERT INTO meta VALUES("last_id",?) ON CONFLICT(key) DO UPDATE SET value=excluded.value`, id)
+	return err
+}
+
+// save writes the items of l that differ from the rows in before
+// and deletes the rows of items no longer in l.
+// Positions only need to keep the items in order, so a stored position
+// is reused whenever it still sorts after the previous item
+func save(tx *sql.Tx, l todo.List, before map[int]row) error {
+	last := -1
+	for i := range l.Items {
+		data, err := json.Marshal(l.Items[i])
+		if err != nil {
+			return err
+		}
+		r := row{position: last + 1, data: string(data)}
+		old, ok := before[l.Items[i].ID]
+		delete(before, l.Items[i].ID)
+		if ok && old.position > last {
+			r.position = old.position
+		}
+		last = r.position
+		if ok && old == r {
+			continue
+		}
+		_, err = tx.Exec(
+			"INSERT INTO todo VALUES(?,?,?) ON CONFLICT(id) DO UPDATE SET position=excluded.position, data=excluded.data",
+			l.Items[i].ID, r.position, r.data)
+		if err != nil {
+			return err
+		}
+	}
+	for id := range before {
+		if _, err := tx.Exec("DELETE FROM todo WHERE id=?", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
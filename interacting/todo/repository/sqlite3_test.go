@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"path/filepath"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getRepo(t *testing.T) *dbRepo {
+	t.Helper()
+	repo, err := NewSQLite3Repo(filepath.Join(t.TempDir(), "todo.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		repo.Close()
+	})
+	return repo
+}
+
+func totalChanges(t *testing.T, r *dbRepo) int {
+	t.Helper()
+	var n int
+	require.NoError(t, r.db.QueryRow("SELECT total_changes()").Scan(&n))
+	return n
+}
+
+func TestSQLite3Repo(t *testing.T) {
+	repo := getRepo(t)
+	tasks := []string{"Task 1", "Task 2", "Task 3"}
+	err := repo.Update(func(l *todo.List) error {
+		for _, task := range tasks {
+			l.Add(task)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	l := todo.List{}
+	require.NoError(t, repo.Load(&l))
+	require.Len(t, l.Items, len(tasks))
+	for i, task := range tasks {
+		assert.Equal(t, i+1, l.Items[i].ID)
+		assert.Equal(t, task, l.Items[i].Task)
+	}
+
+	t.Run("WritesOnlyChanges", func(t *testing.T) {
+		start := totalChanges(t, repo)
+		err := repo.Update(func(l *todo.List) error {
+			return l.Delete(1)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, totalChanges(t, repo)-start, "expected only the deleted row to change")
+
+		start = totalChanges(t, repo)
+		err = repo.Update(func(l *todo.List) error {
+			return l.Complete(3)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, totalChanges(t, repo)-start, "expected only the completed row to change")
+	})
+
+	t.Run("KeepsOrder", func(t *testing.T) {
+		err := repo.Update(func(l *todo.List) error {
+			l.Add("Task 4")
+			return nil
+		})
+		require.NoError(t, err)
+		l := todo.List{}
+		require.NoError(t, repo.Load(&l))
+		require.Len(t, l.Items, 3)
+		assert.Equal(t, []int{2, 3, 4}, []int{l.Items[0].ID, l.Items[1].ID, l.Items[2].ID})
+		assert.True(t, l.Items[1].Done())
+	})
+
+	t.Run("KeepsLastID", func(t *testing.T) {
+		err := repo.Update(func(l *todo.List) error {
+			return l.Delete(4)
+		})
+		require.NoError(t, err)
+		var id int
+		err = repo.Update(func(l *todo.List) error {
+			id = l.Add("Task 5")
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 5, id, "expected the ID of the deleted item not to be reused")
+	})
+
+	t.Run("RollbackOnError", func(t *testing.T) {
+		err := repo.Update(func(l *todo.List) error {
+			l.Add("Not saved")
+			return todo.ErrNotExists
+		})
+		assert.ErrorIs(t, err, todo.ErrNotExists)
+		l := todo.List{}
+		require.NoError(t, repo.Load(&l))
+		assert.Len(t, l.Items, 3)
+	})
+}
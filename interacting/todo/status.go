@@ -0,0 +1,200 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrInvalidStatus   = errors.New("invalid status")
+	ErrInvalidWorkflow = errors.New("invalid workflow")
+)
+
+// Statuses of the default workflow
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in-progress"
+	StatusBlocked    = "blocked"
+	StatusDone       = "done"
+)
+
+// Workflow lists the statuses items move through, in board order.
+// New items start in StatusTodo, the first status, and StatusDone,
+// the last one, marks them completed
+type Workflow []string
+
+// DefaultWorkflow is used unless another one is configured
+var DefaultWorkflow = Workflow{StatusTodo, StatusInProgress, StatusBlocked, StatusDone}
+
+// ParseWorkflow parses a comma separated list of statuses,
+// such as todo,in-progress,review,done
+func ParseWorkflow(s string) (Workflow, error) {
+	var w Workflow
+	for _, status := range strings.Split(s, ",") {
+		status = strings.ToLower(strings.TrimSpace(status))
+		if status == "" || strings.ContainsFunc(status, unicode.IsSpace) || strings.Contains(status, ":") {
+			return nil, fmt.Errorf("%w: invalid status %q", ErrInvalidWorkflow, status)
+		}
+		if slices.Contains(w, status) {
+			return nil, fmt.Errorf("%w: duplicate status %q", ErrInvalidWorkflow, status)
+		}
+		w = append(w, status)
+	}
+	if len(w) < 2 || w[0] != StatusTodo || w[len(w)-1] != StatusDone {
+		return nil, fmt.Errorf("%w: it must start with %s and end with %s", ErrInvalidWorkflow, StatusTodo, StatusDone)
+	}
+	return w, nil
+}
+
+// String formats the workflow in the syntax accepted by ParseWorkflow
+func (w Workflow) String() string {
+	return strings.Join(w, ",")
+}
+
+// Transition records an item moving from one status to another
+type Transition struct {
+	From string
+	To   string
+	At   time.Time
+}
+
+// Done reports whether the item is completed
+func (i item) Done() bool {
+	return i.Status == StatusDone
+}
+
+// itemJSON is the JSON encoding of an item. Done is derived from the
+// status when saving, and gives the status of items saved before
+// statuses existed when loading
+type itemJSON struct {
+	itemFields
+	Done bool
+}
+
+type itemFields item
+
+// MarshalJSON implements json.Marshaler
+func (i item) MarshalJSON() ([]byte, error) {
+	return json.Marshal(itemJSON{itemFields(i), i.Done()})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (i *item) UnmarshalJSON(data []byte) error {
+	var js itemJSON
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	*i = item(js.itemFields)
+	if i.Status == "" {
+		i.Status = StatusTodo
+		if js.Done {
+			i.Status = StatusDone
+		}
+	}
+	return nil
+}
+
+// SetStatus moves the item with the given ID to status, which must be
+// part of the workflow w. Statuses are matched regardless of case, as
+// ParseWorkflow lowercases them. Moving to StatusDone completes the
+// item like Complete, refusing items with open dependencies, and
+// moving out of it reopens the item
+func (l *List) SetStatus(id int, status string, w Workflow) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	status = strings.ToLower(strings.TrimSpace(status))
+	if !slices.Contains(w, status) {
+		return fmt.Errorf("%w: %q is not one of %s", ErrInvalidStatus, status, w)
+	}
+	if status == StatusDone {
+		if err := l.checkBlockers(id); err != nil {
+			return err
+		}
+	}
+	l.setStatus(i, status, time.Now())
+	return nil
+}
+
+// setStatus moves the item at position i to status at the time now,
+// recording the transition.
+// Completing a recurring item adds its next occurrence to the list,
+// unless the occurrence added when it was completed before is still
+// there, so reopening and completing it again adds no duplicate
+func (l *List) setStatus(i int, status string, now time.Time) {
+	ls := l.Items
+	from := ls[i].Status
+	if from == status {
+		return
+	}
+	ls[i].Status = status
+	ls[i].Transitions = append(ls[i].Transitions, Transition{From: from, To: status, At: now})
+	switch {
+	case status == StatusDone:
+		ls[i].CompletedAt = now
+		if _, err := l.Index(ls[i].Spawned); ls[i].Recur != nil && err != nil {
+			id := l.spawn(i, now)
+			l.Items[i].Spawned = id
+		}
+	case from == StatusDone:
+		ls[i].CompletedAt = time.Time{}
+	}
+}
+
+// Board renders the list as a board with a column per status of the
+// workflow w. Statuses of items that are not part of w get a column
+// after the ones of w
+func (l *List) Board(w Workflow) string {
+	columns := slices.Clone(w)
+	cells := map[string][]string{}
+	for _, value := range l.Items {
+		if !slices.Contains(columns, value.Status) {
+			columns = append(columns, value.Status)
+		}
+		cells[value.Status] = append(cells[value.Status], fmt.Sprintf("%d: %s", value.ID, value.Task))
+	}
+	rows := 0
+	for _, c := range cells {
+		rows = max(rows, len(c))
+	}
+
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	var header []string
+	for _, c := range columns {
+		header = append(header, fmt.Sprintf("%s (%d)", strings.ToUpper(c), len(cells[c])))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for r := 0; r < rows; r++ {
+		var row []string
+		for _, c := range columns {
+			cell := ""
+			if r < len(cells[c]) {
+				cell = cells[c][r]
+			}
+			row = append(row, cell)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+	lines := strings.Split(b.String(), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Statuses returns the configured workflow or DefaultWorkflow
+func (c *Catalog) Statuses() Workflow {
+	if len(c.Workflow) == 0 {
+		return DefaultWorkflow
+	}
+	return c.Workflow
+}
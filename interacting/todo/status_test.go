@@ -0,0 +1,100 @@
+package todo_test
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pragprog.com/rggo/interacting/todo"
+	"strings"
+	"testing"
+)
+
+func TestParseWorkflow(t *testing.T) {
+	w, err := todo.ParseWorkflow("todo, In-Progress,review,done")
+	require.NoError(t, err)
+	assert.Equal(t, todo.Workflow{"todo", "in-progress", "review", "done"}, w)
+	assert.Equal(t, "todo,in-progress,review,done", w.String())
+
+	for _, s := range []string{"", "todo", "doing,done", "todo,doing", "todo,doing,doing,done", "todo,,done"} {
+		_, err := todo.ParseWorkflow(s)
+		assert.ErrorIs(t, err, todo.ErrInvalidWorkflow, "expected %q to be rejected", s)
+	}
+}
+
+func TestList_SetStatus(t *testing.T) {
+	l := todo.List{}
+	id := l.Add("Task 1")
+	assert.Equal(t, todo.StatusTodo, l.Items[0].Status)
+
+	require.NoError(t, l.SetStatus(id, todo.StatusInProgress, todo.DefaultWorkflow))
+	require.NoError(t, l.SetStatus(id, "Blocked", todo.DefaultWorkflow))
+	assert.Equal(t, "  1: Task 1 [blocked]\n", l.String(), "expected the status to be lowercased")
+	assert.ErrorIs(t, l.SetStatus(id, "review", todo.DefaultWorkflow), todo.ErrInvalidStatus)
+	assert.ErrorIs(t, l.SetStatus(2, todo.StatusDone, todo.DefaultWorkflow), todo.ErrNotExists)
+
+	require.NoError(t, l.SetStatus(id, todo.StatusDone, todo.DefaultWorkflow))
+	assert.True(t, l.Items[0].Done())
+	assert.False(t, l.Items[0].CompletedAt.IsZero())
+
+	require.NoError(t, l.SetStatus(id, todo.StatusInProgress, todo.DefaultWorkflow))
+	assert.False(t, l.Items[0].Done(), "expected the item to be reopened")
+	assert.True(t, l.Items[0].CompletedAt.IsZero())
+
+	var path []string
+	for _, tr := range l.Items[0].Transitions {
+		path = append(path, tr.From+">"+tr.To)
+	}
+	assert.Equal(t, []string{"todo>in-progress", "in-progress>blocked", "blocked>done", "done>in-progress"}, path)
+}
+
+func TestItem_JSON(t *testing.T) {
+	legacy := `[{"ID":1,"Task":"Old task","Done":true},{"ID":2,"Task":"Open task","Done":false}]`
+	l := todo.List{}
+	require.NoError(t, json.Unmarshal([]byte(legacy), &l))
+	assert.Equal(t, todo.StatusDone, l.Items[0].Status)
+	assert.Equal(t, todo.StatusTodo, l.Items[1].Status)
+
+	require.NoError(t, l.SetStatus(2, todo.StatusBlocked, todo.DefaultWorkflow))
+	js, err := json.Marshal(l)
+	require.NoError(t, err)
+	assert.Contains(t, string(js), `"Status":"done"`)
+	assert.Contains(t, string(js), `"Done":true`, "expected Done for older readers")
+
+	l2 := todo.List{}
+	require.NoError(t, json.Unmarshal(js, &l2))
+	assert.Equal(t, todo.StatusBlocked, l2.Items[1].Status)
+	require.Len(t, l2.Items[1].Transitions, 1)
+}
+
+func TestList_Board(t *testing.T) {
+	l := todo.List{}
+	l.Add("Write report")
+	l.Add("Call vendor")
+	l.Add("Book flights")
+	require.NoError(t, l.SetStatus(2, todo.StatusInProgress, todo.DefaultWorkflow))
+	require.NoError(t, l.Complete(3))
+
+	exp := "TODO (1)         IN-PROGRESS (1)  BLOCKED (0)  DONE (1)\n" +
+		"1: Write report  2: Call vendor                3: Book flights\n"
+	assert.Equal(t, exp, l.Board(todo.DefaultWorkflow))
+
+	w, err := todo.ParseWorkflow("todo,done")
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(strings.Split(l.Board(w), "\n")[0], "IN-PROGRESS (1)"),
+		"expected statuses missing from the workflow to get a column")
+}
+
+func TestMerge_Status(t *testing.T) {
+	base := todo.List{}
+	base.Add("Task 1")
+	ours := clone(t, base)
+	theirs := clone(t, base)
+	require.NoError(t, ours.SetStatus(1, todo.StatusBlocked, todo.DefaultWorkflow))
+	require.NoError(t, theirs.Complete(1))
+
+	merged, conflicts, err := todo.Merge(base, ours, theirs)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	assert.True(t, merged.Items[0].Done(), "expected completion to win")
+	assert.Len(t, merged.Items[0].Transitions, 2, "expected the transitions of both sides")
+}
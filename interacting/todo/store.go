@@ -0,0 +1,40 @@
+package todo
+
+// Store is the interface implemented by the storage backends
+// that persist a List
+type Store interface {
+	// Load reads the whole List from the store
+	Load(l *List) error
+	// Update reads the List, applies fn to it and persists the result.
+	// Nothing is persisted if fn returns an error
+	Update(fn func(*List) error) error
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// fileStore keeps the List as a JSON document in a single file
+type fileStore struct {
+	name string
+	opts []Option
+}
+
+// NewFileStore returns a Store backed by the JSON file name.
+// The options apply to every read and write, see WithPassphrase
+func NewFileStore(name string, opts ...Option) *fileStore {
+	return &fileStore{
+		name: name,
+		opts: opts,
+	}
+}
+
+func (s *fileStore) Load(l *List) error {
+	return l.Get(s.name, s.opts...)
+}
+
+func (s *fileStore) Update(fn func(*List) error) error {
+	return Update(s.name, fn, s.opts...)
+}
+
+func (s *fileStore) Close() error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package todo_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	var s todo.Store = todo.NewFileStore(filepath.Join(t.TempDir(), ".todo.json"))
+	defer s.Close()
+	err := s.Update(func(l *todo.List) error {
+		l.Add("Task 1")
+		l.Add("Task 2")
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Update(func(l *todo.List) error {
+		return l.Delete(1)
+	}))
+	l := todo.List{}
+	require.NoError(t, s.Load(&l))
+	require.Len(t, l.Items, 1)
+	assert.Equal(t, 2, l.Items[0].ID)
+	assert.Equal(t, "Task 2", l.Items[0].Task)
+}
@@ -0,0 +1,175 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var (
+	ErrInvalidParent = errors.New("invalid parent")
+)
+
+// node is an item position in the list along with its depth in the tree
+type node struct {
+	index int
+	depth int
+}
+
+// AddSub creates a new todo item as a child of the item with the
+// given parent ID, in the same list, and returns the ID assigned to it
+func (l *List) AddSub(parent int, task string) (int, error) {
+	p, err := l.Index(parent)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidParent, err)
+	}
+	list := l.Items[p].List
+	id := l.Add(task)
+	l.Items[len(l.Items)-1].Parent = parent
+	l.Items[len(l.Items)-1].List = list
+	return id, nil
+}
+
+// SetParent moves the item with the given ID under the item with the
+// parent ID. A parent ID of 0 moves the item to the top level.
+// Items moved under a parent in another list move to that list
+// along with their subtasks
+func (l *List) SetParent(id, parent int) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
+	}
+	if parent != 0 {
+		p, err := l.Index(parent)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidParent, err)
+		}
+		if parent == id || slices.Contains(l.descendants(id), parent) {
+			return fmt.Errorf("%w: item %d cannot be moved under itself", ErrInvalidParent, id)
+		}
+		l.setList(append([]int{id}, l.descendants(id)...), l.Items[p].ListName())
+	}
+	l.Items[i].Parent = parent
+	return nil
+}
+
+// Progress returns how many of the direct children of the item
+// with the given ID are completed, and how many children it has
+func (l *List) Progress(id int) (int, int) {
+	done, total := 0, 0
+	for _, value := range l.Items {
+		if value.Parent != id || value.ID == id {
+			continue
+		}
+		total++
+		if value.Done() {
+			done++
+		}
+	}
+	return done, total
+}
+
+// CompleteTree completes the item with the given ID along with
+// all of its descendants. Dependencies between items of the tree
+// are ignored, but the tree is refused with ErrBlocked if any of
+// its items depends on an open item outside of it
+func (l *List) CompleteTree(id int) error {
+	if _, err := l.Index(id); err != nil {
+		return err
+	}
+	tree := append([]int{id}, l.descendants(id)...)
+	for _, d := range tree {
+		for _, b := range l.Blockers(d) {
+			if !slices.Contains(tree, b) {
+				return fmt.Errorf("%w: %d is waiting for %d", ErrBlocked, d, b)
+			}
+		}
+	}
+	now := time.Now()
+	for _, d := range tree {
+		i, _ := l.Index(d)
+		if l.Items[i].Done() {
+			continue
+		}
+		l.setStatus(i, StatusDone, now)
+	}
+	return nil
+}
+
+// descendants returns the IDs of all items below the item with the given ID
+func (l *List) descendants(id int) []int {
+	var ids []int
+	queue := []int{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, value := range l.Items {
+			if value.Parent == current && value.ID != id && !slices.Contains(ids, value.ID) {
+				ids = append(ids, value.ID)
+				queue = append(queue, value.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// tree returns the items in depth first order, each child following
+// its parent. Siblings keep their relative order in the list. Items
+// whose parent is missing, and the items of a cycle of parents, which
+// no change makes but edited files can hold, are shown at the top level
+func (l *List) tree() []node {
+	ls := l.Items
+	parents := map[int]int{}
+	for _, value := range ls {
+		parents[value.ID] = value.Parent
+	}
+	// cyclic reports whether the parents of the item lead back to it
+	cyclic := func(id int) bool {
+		seen := map[int]bool{}
+		for p, ok := parents[id]; ok && !seen[p]; p, ok = parents[p] {
+			if p == id {
+				return true
+			}
+			seen[p] = true
+		}
+		return false
+	}
+	children := map[int][]int{}
+	var roots []int
+	for i, value := range ls {
+		if _, ok := parents[value.Parent]; !ok || cyclic(value.ID) {
+			roots = append(roots, i)
+			continue
+		}
+		children[value.Parent] = append(children[value.Parent], i)
+	}
+
+	var nodes []node
+	visited := map[int]bool{}
+	var walk func(i, depth int)
+	walk = func(i, depth int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		nodes = append(nodes, node{index: i, depth: depth})
+		for _, c := range children[ls[i].ID] {
+			walk(c, depth+1)
+		}
+	}
+	for _, i := range roots {
+		walk(i, 0)
+	}
+	return nodes
+}
+
+// Order returns the IDs of the items in the order String shows them,
+// one per line
+func (l *List) Order() []int {
+	var ids []int
+	for _, n := range l.tree() {
+		ids = append(ids, l.Items[n.index].ID)
+	}
+	return ids
+}
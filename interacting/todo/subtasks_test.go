@@ -0,0 +1,70 @@
+package todo_test
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+)
+
+func TestList_Subtasks(t *testing.T) {
+	l := todo.List{}
+	release := l.Add("Release")
+	other := l.Add("Other")
+	tag, err := l.AddSub(release, "Tag")
+	require.NoError(t, err)
+	notes, err := l.AddSub(release, "Notes")
+	require.NoError(t, err)
+	draft, err := l.AddSub(notes, "Draft")
+	require.NoError(t, err)
+	_, err = l.AddSub(100, "Orphan")
+	assert.ErrorIs(t, err, todo.ErrInvalidParent)
+
+	t.Run("String", func(t *testing.T) {
+		require.NoError(t, l.Complete(tag))
+		exp := "  1: Release [1/2]\n" +
+			"X   3: Tag\n" +
+			"    4: Notes [0/1]\n" +
+			"      5: Draft\n" +
+			"  2: Other\n"
+		assert.Equal(t, exp, l.String())
+		assert.Equal(t, []int{release, tag, notes, draft, other}, l.Order())
+	})
+
+	t.Run("SetParent", func(t *testing.T) {
+		assert.ErrorIs(t, l.SetParent(release, draft), todo.ErrInvalidParent, "expected cycle to be rejected")
+		assert.ErrorIs(t, l.SetParent(release, release), todo.ErrInvalidParent)
+		require.NoError(t, l.SetParent(other, release))
+		done, total := l.Progress(release)
+		assert.Equal(t, 1, done)
+		assert.Equal(t, 3, total)
+		require.NoError(t, l.SetParent(other, 0))
+	})
+
+	t.Run("CompleteTree", func(t *testing.T) {
+		require.NoError(t, l.CompleteTree(notes))
+		i, err := l.Index(draft)
+		require.NoError(t, err)
+		assert.True(t, l.Items[i].Done(), "expected children to be completed")
+		i, err = l.Index(release)
+		require.NoError(t, err)
+		assert.False(t, l.Items[i].Done(), "expected parent to stay open")
+	})
+
+	t.Run("DeleteSubtree", func(t *testing.T) {
+		require.NoError(t, l.Delete(release))
+		require.Len(t, l.Items, 1)
+		assert.Equal(t, other, l.Items[0].ID)
+	})
+}
+
+func TestList_ParentCycle(t *testing.T) {
+	// SetParent rejects cycles, but an edited file can still hold one
+	data := `[{"ID":1,"Task":"A","Parent":2},{"ID":2,"Task":"B","Parent":1},` +
+		`{"ID":3,"Task":"C","Parent":2},{"ID":4,"Task":"D","Parent":4}]`
+	l := todo.List{}
+	require.NoError(t, json.Unmarshal([]byte(data), &l))
+	assert.Equal(t, []int{1, 2, 3, 4}, l.Order(), "expected the items of a cycle at the top level")
+	assert.Contains(t, l.String(), "  2: B [0/2]\n    3: C\n", "expected the children of a cycle below it")
+}
@@ -0,0 +1,163 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrTemplateExists    = errors.New("template already exists")
+	ErrTemplateNotExists = errors.New("template does not exist")
+	ErrInvalidTemplate   = errors.New("invalid template")
+	ErrInvalidParam      = errors.New("invalid template parameter")
+)
+
+// paramRe matches the parameters of the tasks of a template, such as {version}
+var paramRe = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Template is a set of items added together, such as the steps of a
+// release. The tasks may hold parameters such as {version}, which are
+// replaced when the template is instantiated. When Task is set, it
+// is added as well with the items as its subtasks, making them a
+// checklist showing its progress
+type Template struct {
+	Task  string `json:",omitempty"`
+	Items []string
+}
+
+// Params returns the names of the parameters of the template in the
+// order they first appear
+func (t Template) Params() []string {
+	var names []string
+	for _, task := range append([]string{t.Task}, t.Items...) {
+		for _, m := range paramRe.FindAllStringSubmatch(task, -1) {
+			if !slices.Contains(names, m[1]) {
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// String formats the template with one item per line, indented
+// below the task when it is set
+func (t Template) String() string {
+	var formatted, indent string
+	if t.Task != "" {
+		formatted = t.Task + "\n"
+		indent = "  "
+	}
+	for _, task := range t.Items {
+		formatted += indent + "- " + task + "\n"
+	}
+	return formatted
+}
+
+// expand replaces the parameters of task with their values
+func expand(task string, params map[string]string) string {
+	return paramRe.ReplaceAllStringFunc(task, func(p string) string {
+		return params[p[1:len(p)-1]]
+	})
+}
+
+// Instantiate adds the items of the template to the list, replacing
+// the parameters with their values. Every parameter of the template
+// needs a value, and values for unknown parameters are rejected.
+// It returns the IDs of the items added at the top level: the task
+// of the template when it is set, or else all the items
+func (l *List) Instantiate(t Template, params map[string]string) ([]int, error) {
+	names := t.Params()
+	for _, name := range names {
+		if _, ok := params[name]; !ok {
+			return nil, fmt.Errorf("%w: missing value for {%s}", ErrInvalidParam, name)
+		}
+	}
+	for name := range params {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("%w: the template has no {%s}", ErrInvalidParam, name)
+		}
+	}
+
+	if t.Task == "" {
+		var ids []int
+		for _, task := range t.Items {
+			ids = append(ids, l.Add(expand(task, params)))
+		}
+		return ids, nil
+	}
+	parent := l.Add(expand(t.Task, params))
+	for _, task := range t.Items {
+		if _, err := l.AddSub(parent, expand(task, params)); err != nil {
+			return nil, err
+		}
+	}
+	return []int{parent}, nil
+}
+
+// ParseParams parses parameters given as name=value pairs
+func ParseParams(pairs []string) (map[string]string, error) {
+	params := map[string]string{}
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || !paramRe.MatchString("{"+name+"}") {
+			return nil, fmt.Errorf("%w: expected name=value, got %q", ErrInvalidParam, pair)
+		}
+		params[name] = value
+	}
+	return params, nil
+}
+
+// AddTemplate adds the template with the given name to the catalog
+func (c *Catalog) AddTemplate(name string, t Template) error {
+	if name == "" || strings.ContainsFunc(name, unicode.IsSpace) || strings.Contains(name, ":") {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalidTemplate, name)
+	}
+	if _, ok := c.Templates[name]; ok {
+		return fmt.Errorf("%w: %s", ErrTemplateExists, name)
+	}
+	if len(t.Items) == 0 {
+		return fmt.Errorf("%w: %s has no items", ErrInvalidTemplate, name)
+	}
+	for _, task := range append([]string{t.Task}, t.Items...) {
+		if strings.Count(task, "{") != len(paramRe.FindAllString(task, -1)) {
+			return fmt.Errorf("%w: unexpected { in %q, parameters are like {version}", ErrInvalidTemplate, task)
+		}
+	}
+	if c.Templates == nil {
+		c.Templates = map[string]Template{}
+	}
+	c.Templates[name] = t
+	return nil
+}
+
+// Template returns the template with the given name
+func (c *Catalog) Template(name string) (Template, error) {
+	t, ok := c.Templates[name]
+	if !ok {
+		return Template{}, fmt.Errorf("%w: %s", ErrTemplateNotExists, name)
+	}
+	return t, nil
+}
+
+// RemoveTemplate removes the template with the given name
+func (c *Catalog) RemoveTemplate(name string) error {
+	if _, ok := c.Templates[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrTemplateNotExists, name)
+	}
+	delete(c.Templates, name)
+	return nil
+}
+
+// TemplateNames returns the names of the templates, sorted
+func (c *Catalog) TemplateNames() []string {
+	var names []string
+	for name := range c.Templates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,89 @@
+package todo_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"pragprog.com/rggo/interacting/todo"
+	"testing"
+)
+
+func TestTemplates(t *testing.T) {
+	release := todo.Template{
+		Task:  "Release {version}",
+		Items: []string{"Tag v{version}", "Update the changelog", "Announce {version} on {channel}"},
+	}
+	name := filepath.Join(t.TempDir(), ".todo.json.lists")
+
+	t.Run("AddTemplate", func(t *testing.T) {
+		err := todo.UpdateCatalog(name, func(c *todo.Catalog) error {
+			require.NoError(t, c.AddTemplate("release", release))
+			assert.ErrorIs(t, c.AddTemplate("release", release), todo.ErrTemplateExists)
+			assert.ErrorIs(t, c.AddTemplate("my release", release), todo.ErrInvalidTemplate)
+			assert.ErrorIs(t, c.AddTemplate("empty", todo.Template{Task: "Empty"}), todo.ErrInvalidTemplate)
+			assert.ErrorIs(t, c.AddTemplate("typo", todo.Template{Items: []string{"Tag {version"}}), todo.ErrInvalidTemplate)
+			return c.AddTemplate("groceries", todo.Template{Items: []string{"Milk", "Bread"}})
+		})
+		require.NoError(t, err)
+
+		c := &todo.Catalog{}
+		require.NoError(t, c.Get(name))
+		assert.Equal(t, []string{"groceries", "release"}, c.TemplateNames())
+		got, err := c.Template("release")
+		require.NoError(t, err)
+		assert.Equal(t, release, got)
+		assert.Equal(t, []string{"version", "channel"}, got.Params())
+		assert.Equal(t, "Release {version}\n  - Tag v{version}\n  - Update the changelog\n  - Announce {version} on {channel}\n", got.String())
+		_, err = c.Template("deploy")
+		assert.ErrorIs(t, err, todo.ErrTemplateNotExists)
+	})
+
+	t.Run("Instantiate", func(t *testing.T) {
+		l := todo.List{}
+		l.Add("Other")
+		_, err := l.Instantiate(release, map[string]string{"version": "1.2"})
+		assert.ErrorIs(t, err, todo.ErrInvalidParam, "expected a missing value to be rejected")
+		_, err = l.Instantiate(release, map[string]string{"version": "1.2", "channel": "irc", "date": "today"})
+		assert.ErrorIs(t, err, todo.ErrInvalidParam, "expected an unknown parameter to be rejected")
+
+		l = todo.List{}
+		l.Add("Other")
+		params, err := todo.ParseParams([]string{"version=1.2", "channel=the blog"})
+		require.NoError(t, err)
+		ids, err := l.Instantiate(release, params)
+		require.NoError(t, err)
+		assert.Equal(t, []int{2}, ids)
+		exp := "  1: Other\n" +
+			"  2: Release 1.2 [0/3]\n" +
+			"    3: Tag v1.2\n" +
+			"    4: Update the changelog\n" +
+			"    5: Announce 1.2 on the blog\n"
+		assert.Equal(t, exp, l.String())
+
+		ids, err = l.Instantiate(todo.Template{Items: []string{"Milk", "Bread"}}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []int{6, 7}, ids)
+	})
+
+	t.Run("ParseParams", func(t *testing.T) {
+		_, err := todo.ParseParams([]string{"version"})
+		assert.ErrorIs(t, err, todo.ErrInvalidParam)
+		_, err = todo.ParseParams([]string{"1x=2"})
+		assert.ErrorIs(t, err, todo.ErrInvalidParam)
+		params, err := todo.ParseParams([]string{"url=a=b"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"url": "a=b"}, params)
+	})
+
+	t.Run("RemoveTemplate", func(t *testing.T) {
+		err := todo.UpdateCatalog(name, func(c *todo.Catalog) error {
+			require.NoError(t, c.RemoveTemplate("groceries"))
+			assert.ErrorIs(t, c.RemoveTemplate("groceries"), todo.ErrTemplateNotExists)
+			return nil
+		})
+		require.NoError(t, err)
+		c := &todo.Catalog{}
+		require.NoError(t, c.Get(name))
+		assert.Equal(t, []string{"release"}, c.TemplateNames())
+	})
+}
@@ -1,84 +1,261 @@
 package todo
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 )
 
+var (
+	ErrNotExists = errors.New("item does not exist")
+)
+
 type item struct {
+	ID          int
 	Task        string
-	Done        bool
+	Status      string
 	CreatedAt   time.Time
 	CompletedAt time.Time
+	Due         time.Time
+	Priority    string
+	Tags        []string
+	Recur       *Recurrence
+	Parent      int
+	List        string       `json:",omitempty"`
+	Transitions []Transition `json:",omitempty"`
+	DependsOn   []int        `json:",omitempty"`
+	Spawned     int          `json:",omitempty"`
+}
+
+// List holds the todo items along with the highest ID ever assigned
+// to one of them, so the ID of a deleted item is never handed out
+// again and IDs keep pointing at the same item for good
+type List struct {
+	Items  []item
+	LastID int `json:",omitempty"`
+	// from is the whole list the items were selected from, so
+	// dependencies on items left out still count
+	from *List
 }
 
-type List []item
+// whole returns the whole list the items were selected from
+func (l *List) whole() *List {
+	if l.from != nil {
+		return l.from
+	}
+	return l
+}
 
-// String implements Stringer interface
+// UnmarshalJSON implements json.Unmarshaler. Lists saved before the
+// last ID was kept are a bare array of items
+func (l *List) UnmarshalJSON(data []byte) error {
+	*l = List{}
+	if d := bytes.TrimSpace(data); len(d) > 0 && d[0] == '[' {
+		return json.Unmarshal(data, &l.Items)
+	}
+	type list List
+	return json.Unmarshal(data, (*list)(l))
+}
+
+// String implements Stringer interface.
+// Subtasks are indented below their parent, which shows
+// how many of its children are completed, and open items
+// show the open items they depend on
 func (l *List) String() string {
 	var formatted string
-	for index, value := range *l {
+	now := time.Now()
+	for _, n := range l.tree() {
+		value := l.Items[n.index]
 		prefix := "  "
-		if value.Done {
+		if value.Done() {
 			prefix = "X "
 		}
-		formatted += fmt.Sprintf("%s%d: %s\n", prefix, index+1, value.Task)
+		desc := value.describe(now)
+		if done, total := l.Progress(value.ID); total > 0 {
+			desc += fmt.Sprintf(" [%d/%d]", done, total)
+		}
+		if blockers := l.Blockers(value.ID); len(blockers) > 0 && !value.Done() {
+			desc += fmt.Sprintf(" [blocked by %s]", formatIDs(blockers))
+		}
+		formatted += fmt.Sprintf("%s%s%d: %s\n", prefix, strings.Repeat("  ", n.depth), value.ID, desc)
 	}
 	return formatted
 }
 
-// Add creates a new todo item and appends it to the list
-func (l *List) Add(task string) {
+// describe formats the task along with its priority, tags, due date
+// and the status of items neither done nor still to do
+func (i item) describe(now time.Time) string {
+	desc := i.Task
+	if i.Priority != "" {
+		desc = fmt.Sprintf("(%s) %s", i.Priority, desc)
+	}
+	for _, tag := range i.Tags {
+		desc += " #" + tag
+	}
+	if !i.Due.IsZero() {
+		desc += fmt.Sprintf(" (due %s)", i.Due.Format(time.DateOnly))
+	}
+	if !i.Done() && i.Status != StatusTodo {
+		desc += fmt.Sprintf(" [%s]", i.Status)
+	}
+	if i.Overdue(now) {
+		desc += " [overdue]"
+	}
+	return desc
+}
+
+// nextID returns an ID greater than any ID assigned so far,
+// without reserving it
+func (l *List) nextID() int {
+	id := l.LastID
+	for _, value := range l.Items {
+		id = max(id, value.ID)
+	}
+	return id + 1
+}
+
+// newID reserves and returns a new ID, never handed out before
+func (l *List) newID() int {
+	l.LastID = l.nextID()
+	return l.LastID
+}
+
+// Index returns the position in the list of the item with the given ID
+func (l *List) Index(id int) (int, error) {
+	for i, value := range l.Items {
+		if value.ID == id {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("%w: %d", ErrNotExists, id)
+}
+
+// Add creates a new todo item and appends it to the list.
+// It returns the ID assigned to the new item
+func (l *List) Add(task string) int {
 	t := item{
+		ID:          l.newID(),
 		Task:        task,
-		Done:        false,
+		Status:      StatusTodo,
 		CreatedAt:   time.Now(),
 		CompletedAt: time.Time{},
 	}
-	*l = append(*l, t)
+	l.Items = append(l.Items, t)
+	return t.ID
 }
 
-// Complete marks a todo item as completed by
-// setting Done = true and CompletedAt to the current time
-func (l *List) Complete(i int) error {
-	ls := *l
-	if i <= 0 || i > len(ls) {
-		return fmt.Errorf("item %d does not exist", i)
+// Complete marks the todo item with the given ID as completed by
+// moving it to StatusDone and setting CompletedAt to the current time.
+// Completing a recurring item adds its next occurrence to the list.
+// Items depending on open items are refused with ErrBlocked,
+// use ForceComplete to complete them anyway
+func (l *List) Complete(id int) error {
+	i, err := l.Index(id)
+	if err != nil {
+		return err
 	}
-
-	// adjusting index for 0 based index
-	ls[i-1].Done = true
-	ls[i-1].CompletedAt = time.Now()
-
+	if err := l.checkBlockers(id); err != nil {
+		return err
+	}
+	l.setStatus(i, StatusDone, time.Now())
 	return nil
 }
 
-// Delete deletes a todo item from the list
-func (l *List) Delete(i int) error {
-	ls := *l
-	if i < 0 || i > len(ls) {
-		return fmt.Errorf("item %d does not exist", i)
+// Delete deletes the todo item with the given ID from the list
+// along with all of its subtasks, and drops the dependencies
+// of other items on them
+func (l *List) Delete(id int) error {
+	if _, err := l.Index(id); err != nil {
+		return err
 	}
-	*l = append(ls[:i-1], ls[i:]...)
+	ids := append(l.descendants(id), id)
+	l.Items = slices.DeleteFunc(l.Items, func(value item) bool {
+		return slices.Contains(ids, value.ID)
+	})
+	l.dropDependencies(ids)
 	return nil
 }
 
 // Save encodes the List as JSON and saves it
-// using the provided file name
-func (l *List) Save(name string) error {
+// using the provided file name, encrypted when a passphrase is
+// given with WithPassphrase.
+// The data is written to a temporary file first and then renamed
+// over the original, so a crash never leaves a partially written file
+func (l *List) Save(name string, opts ...Option) error {
 	js, err := json.Marshal(l)
 	if err != nil {
 		return fmt.Errorf("unable to encode the List as JSON: %w", err)
 	}
-	return os.WriteFile(name, js, 0644)
+	data, err := newOptions(opts).seal(js)
+	if err != nil {
+		return fmt.Errorf("unable to encrypt the List: %w", err)
+	}
+	return writeFileAtomic(name, data, 0644)
+}
+
+// writeFileAtomic writes data to a temporary file in the same
+// directory as name and renames it to name once it is safely on disk.
+// An existing file keeps its mode, perm only applies to new files
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	if fi, err := os.Stat(name); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("unable to create a temporary file for %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Update locks the provided file name, reads the List from it,
+// applies fn and saves the result before releasing the lock.
+// The List is not saved if fn returns an error.
+// Use Update for every change so concurrent writers never lose edits
+func Update(name string, fn func(*List) error, opts ...Option) error {
+	unlock, err := lockFile(name)
+	if err != nil {
+		return fmt.Errorf("unable to lock the file name %s: %w", name, err)
+	}
+	defer unlock()
+
+	l := &List{}
+	if err := l.Get(name, opts...); err != nil {
+		return err
+	}
+	if err := fn(l); err != nil {
+		return err
+	}
+	return l.Save(name, opts...)
 }
 
 // Get opens the provided file name, decodes
-// the JSON data and parses it into a List
-func (l *List) Get(name string) error {
+// the JSON data and parses it into a List.
+// Encrypted files require the passphrase given with WithPassphrase,
+// while plaintext files are read with or without one.
+// Items saved before IDs were introduced are assigned one
+func (l *List) Get(name string, opts ...Option) error {
 	file, err := os.ReadFile(name)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -90,5 +267,25 @@ func (l *List) Get(name string) error {
 	if len(file) == 0 {
 		return nil
 	}
-	return json.Unmarshal(file, l)
+	if file, err = newOptions(opts).open(file); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := json.Unmarshal(file, l); err != nil {
+		return err
+	}
+	l.migrate()
+	return nil
+}
+
+// migrate assigns IDs to legacy items that were saved without one,
+// keeping the IDs already present in the list untouched, and makes
+// sure the last ID covers the IDs of the items
+func (l *List) migrate() {
+	ls := l.Items
+	for i := range ls {
+		if ls[i].ID == 0 {
+			ls[i].ID = l.newID()
+		}
+	}
+	l.LastID = l.nextID() - 1
 }
@@ -1,10 +1,15 @@
 package todo_test
 
 import (
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"os"
+	"path/filepath"
 	"pragprog.com/rggo/interacting/todo"
+	"runtime"
+	"sync"
 	"testing"
 )
 
@@ -13,8 +18,8 @@ func TestList_Add(t *testing.T) {
 	task := "New"
 	l.Add(task)
 
-	if l[0].Task != task {
-		t.Errorf("expected %s, got %s", task, l[0].Task)
+	if l.Items[0].Task != task {
+		t.Errorf("expected %s, got %s", task, l.Items[0].Task)
 	}
 }
 
@@ -24,17 +29,17 @@ func TestList_Complete(t *testing.T) {
 	task2 := "Another ToDo Item"
 	l.Add(task)
 	l.Add(task2)
-	assert.Equal(t, task, l[0].Task, "expected %s, got %s", task, l[0].Task)
-	assert.False(t, l[0].Done, "new task should not be completed")
+	assert.Equal(t, task, l.Items[0].Task, "expected %s, got %s", task, l.Items[0].Task)
+	assert.False(t, l.Items[0].Done(), "new task should not be completed")
 	err := l.Complete(1)
 	require.NoError(t, err, "expected no error")
-	assert.True(t, l[0].Done, "new task should be completed")
+	assert.True(t, l.Items[0].Done(), "new task should be completed")
 
-	assert.Equal(t, task2, l[1].Task)
-	assert.False(t, l[1].Done)
+	assert.Equal(t, task2, l.Items[1].Task)
+	assert.False(t, l.Items[1].Done())
 	err = l.Complete(2)
 	require.NoError(t, err)
-	assert.True(t, l[1].Done)
+	assert.True(t, l.Items[1].Done())
 }
 
 func TestList_Delete(t *testing.T) {
@@ -47,11 +52,34 @@ func TestList_Delete(t *testing.T) {
 	for _, value := range tasks {
 		l.Add(value)
 	}
-	assert.Equal(t, tasks[0], l[0].Task, "expected %q, got %q", tasks[0], l[0].Task)
+	assert.Equal(t, tasks[0], l.Items[0].Task, "expected %q, got %q", tasks[0], l.Items[0].Task)
 	err := l.Delete(2)
 	require.NoError(t, err, "expected no error")
-	assert.Equal(t, len(l), 2, "expected list length %d, got %d", 2, len(l))
-	assert.Equal(t, tasks[2], l[1].Task, "expected %q, got %q", tasks[2], l[1].Task)
+	assert.Equal(t, len(l.Items), 2, "expected list length %d, got %d", 2, len(l.Items))
+	assert.Equal(t, tasks[2], l.Items[1].Task, "expected %q, got %q", tasks[2], l.Items[1].Task)
+	assert.Equal(t, 3, l.Items[1].ID, "expected ID %d to be kept after delete, got %d", 3, l.Items[1].ID)
+	err = l.Complete(3)
+	require.NoError(t, err, "expected no error")
+	assert.True(t, l.Items[1].Done(), "item with ID 3 should be completed")
+	err = l.Delete(2)
+	assert.ErrorIs(t, err, todo.ErrNotExists, "expected error %q, got %q", todo.ErrNotExists, err)
+}
+
+func TestList_AddID(t *testing.T) {
+	l := todo.List{}
+	assert.Equal(t, 1, l.Add("Task 1"))
+	assert.Equal(t, 2, l.Add("Task 2"))
+	require.NoError(t, l.Delete(1))
+	assert.Equal(t, 3, l.Add("Task 3"), "expected new ID greater than existing IDs")
+	require.NoError(t, l.Delete(3))
+	assert.Equal(t, 4, l.Add("Task 4"), "expected the ID of a deleted item not to be reused")
+
+	tf := filepath.Join(t.TempDir(), "todo.json")
+	require.NoError(t, l.Delete(4))
+	require.NoError(t, l.Save(tf))
+	l2 := todo.List{}
+	require.NoError(t, l2.Get(tf))
+	assert.Equal(t, 5, l2.Add("Task 5"), "expected the last ID to be saved with the list")
 }
 
 func TestList_SaveGet(t *testing.T) {
@@ -59,7 +87,7 @@ func TestList_SaveGet(t *testing.T) {
 	l2 := todo.List{}
 	task := "New Task"
 	l1.Add(task)
-	assert.Equal(t, l1[0].Task, task, "expected %q, got %q", task, l1[0].Task)
+	assert.Equal(t, l1.Items[0].Task, task, "expected %q, got %q", task, l1.Items[0].Task)
 	tf, err := os.CreateTemp("", "")
 	require.NoError(t, err, "failed to create a temp file: %w", err)
 	defer func() {
@@ -71,5 +99,78 @@ func TestList_SaveGet(t *testing.T) {
 	require.NoError(t, err, "failed to save the list to file: %w", err)
 	err = l2.Get(tf.Name())
 	require.NoError(t, err, "failed to get the list from file: %w", err)
-	assert.Equal(t, l1[0].Task, l2[0].Task, "expected task %q match task %q", l1[0].Task, l2[0].Task)
+	assert.Equal(t, l1.Items[0].Task, l2.Items[0].Task, "expected task %q match task %q", l1.Items[0].Task, l2.Items[0].Task)
+}
+
+func TestList_SaveKeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on Windows")
+	}
+	tf := filepath.Join(t.TempDir(), "todo.json")
+	l := todo.List{}
+	l.Add("Task 1")
+	require.NoError(t, l.Save(tf))
+	fi, err := os.Stat(tf)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), fi.Mode().Perm())
+
+	require.NoError(t, os.Chmod(tf, 0600))
+	require.NoError(t, l.Save(tf))
+	fi, err = os.Stat(tf)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), "expected the mode of the file to be kept")
+}
+
+func TestList_GetLegacy(t *testing.T) {
+	legacy := `[{"Task":"Task 1","Done":true,"CreatedAt":"2023-12-23T21:43:42Z","CompletedAt":"2023-12-23T21:52:50Z"},` +
+		`{"Task":"Task 2","Done":false,"CreatedAt":"2023-12-23T21:43:42Z","CompletedAt":"0001-01-01T00:00:00Z"}]`
+	tf, err := os.CreateTemp("", "")
+	require.NoError(t, err, "failed to create a temp file: %w", err)
+	defer os.Remove(tf.Name())
+	_, err = tf.WriteString(legacy)
+	require.NoError(t, err)
+	require.NoError(t, tf.Close())
+	l := todo.List{}
+	require.NoError(t, l.Get(tf.Name()))
+	require.Len(t, l.Items, 2)
+	assert.Equal(t, 1, l.Items[0].ID)
+	assert.Equal(t, 2, l.Items[1].ID)
+	assert.Equal(t, 3, l.Add("Task 3"))
+}
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, ".todo.json")
+	workers := 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := todo.Update(name, func(l *todo.List) error {
+				l.Add(fmt.Sprintf("Task %d", i))
+				return nil
+			})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	l := todo.List{}
+	require.NoError(t, l.Get(name))
+	assert.Len(t, l.Items, workers, "expected no lost updates")
+
+	errFail := errors.New("fail")
+	err := todo.Update(name, func(l *todo.List) error {
+		l.Add("Not saved")
+		return errFail
+	})
+	assert.ErrorIs(t, err, errFail)
+	require.NoError(t, l.Get(name))
+	assert.Len(t, l.Items, workers, "expected list not saved after an error")
+
+	tmp, err := filepath.Glob(name + ".tmp*")
+	require.NoError(t, err)
+	assert.Empty(t, tmp, "expected no temporary files left behind")
 }
@@ -0,0 +1,348 @@
+package todo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrInvalidTodoTxt = errors.New("invalid todo.txt line")
+)
+
+var priorityRe = regexp.MustCompile(`^\([A-Z]\)$`)
+
+// ParseTodoTxt reads items in the todo.txt format, one per line.
+// Besides the standard completion marker, priority, dates, +project
+// and @context tokens, the key:value extras written by WriteTodoTxt
+// restore IDs, subtasks, lists, statuses, recurrence and exact timestamps.
+// Projects become tags and contexts become tags starting with @.
+// Words starting with a backslash are text, without the backslash.
+// Items without an id: extra get an ID of 0, see List.Import
+func ParseTodoTxt(r io.Reader) (List, error) {
+	l := List{}
+	s := bufio.NewScanner(r)
+	n := 0
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		i, err := parseTodoTxtLine(line)
+		if err != nil {
+			return List{}, fmt.Errorf("line %d: %w", n, err)
+		}
+		l.Items = append(l.Items, i)
+	}
+	return l, s.Err()
+}
+
+// WriteTodoTxt writes the list in the todo.txt format, one item per line.
+// Words of the task that would read back as something else, such as
+// a known key:value extra or a tag, are escaped with a backslash
+func (l *List) WriteTodoTxt(w io.Writer) error {
+	for _, value := range l.Items {
+		if _, err := fmt.Fprintln(w, value.todoTxt()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import adds items read from another list, such as a todo.txt file.
+// Their IDs belong to that list, so every item gets a new ID and the
+// parent and dependencies among the imported items follow it, while
+// references to items left out of the import are dropped.
+// Items without a creation date get the current time.
+// Dependencies closing a cycle are dropped and reported.
+// Use Sync to update the list with items exported from it
+func (l *List) Import(items List) []Conflict {
+	ids := map[int]int{}
+	start := len(l.Items)
+	for _, value := range items.Items {
+		id := l.newID()
+		if value.ID != 0 {
+			ids[value.ID] = id
+		}
+		value.ID = id
+		if value.CreatedAt.IsZero() {
+			value.CreatedAt = time.Now()
+		}
+		l.Items = append(l.Items, value)
+	}
+	for i := start; i < len(l.Items); i++ {
+		l.Items[i].Parent = ids[l.Items[i].Parent]
+		l.Items[i].Spawned = ids[l.Items[i].Spawned]
+		var deps []int
+		for _, dep := range l.Items[i].DependsOn {
+			if id, ok := ids[dep]; ok {
+				deps = append(deps, id)
+			}
+		}
+		slices.Sort(deps)
+		l.Items[i].DependsOn = deps
+	}
+	return l.pruneDependencies()
+}
+
+// Sync merges items exported from the list back into it. Items whose
+// ID is already in the list replace the existing item, keeping its
+// status transitions, which todo.txt does not hold, along with one to
+// the new status if it changed. The other ones are appended. Items without an ID are new, so they get a new ID
+// and, when the line had no creation date, the current time.
+// Dependencies on missing items or closing a cycle are dropped and
+// reported
+func (l *List) Sync(items List) []Conflict {
+	for _, value := range items.Items {
+		if value.ID == 0 {
+			continue
+		}
+		if i, err := l.Index(value.ID); err == nil {
+			value.Transitions = l.Items[i].Transitions
+			if from := l.Items[i].Status; from != value.Status {
+				at := value.CompletedAt
+				if !value.Done() || at.IsZero() {
+					at = time.Now()
+				}
+				value.Transitions = append(slices.Clone(value.Transitions), Transition{From: from, To: value.Status, At: at})
+			}
+			l.Items[i] = value
+			continue
+		}
+		l.Items = append(l.Items, value)
+		l.LastID = max(l.LastID, value.ID)
+	}
+	for _, value := range items.Items {
+		if value.ID != 0 {
+			continue
+		}
+		value.ID = l.newID()
+		if value.CreatedAt.IsZero() {
+			value.CreatedAt = time.Now()
+		}
+		l.Items = append(l.Items, value)
+	}
+	return l.pruneDependencies()
+}
+
+// todoTxt formats the item as a todo.txt line
+func (i item) todoTxt() string {
+	var parts []string
+	if i.Done() {
+		parts = append(parts, "x")
+		if !i.CompletedAt.IsZero() {
+			parts = append(parts, i.CompletedAt.Local().Format(time.DateOnly))
+		}
+	} else if i.Priority != "" {
+		parts = append(parts, "("+i.Priority+")")
+	}
+	// A single date after the completion marker is the completion date,
+	// so the creation date only goes along with a completion date
+	if !i.CreatedAt.IsZero() && (!i.Done() || !i.CompletedAt.IsZero()) {
+		parts = append(parts, i.CreatedAt.Local().Format(time.DateOnly))
+	}
+	for n, word := range strings.Fields(i.Task) {
+		parts = append(parts, escapeTodoTxt(word, n == 0))
+	}
+	for _, tag := range i.Tags {
+		if strings.HasPrefix(tag, "@") {
+			parts = append(parts, tag)
+			continue
+		}
+		parts = append(parts, "+"+tag)
+	}
+	if !i.Due.IsZero() {
+		parts = append(parts, "due:"+formatDue(i.Due))
+	}
+	if i.Recur != nil {
+		parts = append(parts, "rec:"+i.Recur.String())
+	}
+	if i.Done() && i.Priority != "" {
+		parts = append(parts, "pri:"+i.Priority)
+	}
+	if i.Parent != 0 {
+		parts = append(parts, fmt.Sprintf("parent:%d", i.Parent))
+	}
+	if i.List != "" {
+		parts = append(parts, "list:"+i.List)
+	}
+	if !i.Done() && i.Status != StatusTodo {
+		parts = append(parts, "status:"+i.Status)
+	}
+	if len(i.DependsOn) > 0 {
+		parts = append(parts, "dep:"+formatIDs(i.DependsOn))
+	}
+	if i.Spawned != 0 {
+		parts = append(parts, fmt.Sprintf("spawned:%d", i.Spawned))
+	}
+	if i.ID != 0 {
+		parts = append(parts, fmt.Sprintf("id:%d", i.ID))
+	}
+	if !i.CreatedAt.IsZero() {
+		parts = append(parts, "created:"+i.CreatedAt.Format(time.RFC3339Nano))
+	}
+	if i.Done() && !i.CompletedAt.IsZero() {
+		parts = append(parts, "completed:"+i.CompletedAt.Format(time.RFC3339Nano))
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseTodoTxtLine parses a single todo.txt line into an item
+func parseTodoTxtLine(line string) (item, error) {
+	i := item{Status: StatusTodo}
+	tokens := strings.Fields(line)
+	if tokens[0] == "x" {
+		i.Status = StatusDone
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 && priorityRe.MatchString(tokens[0]) {
+		i.Priority = tokens[0][1:2]
+		tokens = tokens[1:]
+	}
+	var dates []time.Time
+	for len(tokens) > 0 && len(dates) < 2 {
+		d, err := time.ParseInLocation(time.DateOnly, tokens[0], time.Local)
+		if err != nil {
+			break
+		}
+		dates = append(dates, d)
+		tokens = tokens[1:]
+	}
+	switch {
+	case i.Done() && len(dates) == 2:
+		i.CompletedAt, i.CreatedAt = dates[0], dates[1]
+	case i.Done() && len(dates) == 1:
+		i.CompletedAt = dates[0]
+	case len(dates) > 0:
+		i.CreatedAt = dates[0]
+	}
+
+	var text []string
+	for _, token := range tokens {
+		switch {
+		case len(token) > 1 && token[0] == '\\':
+			text = append(text, token[1:])
+		case len(token) > 1 && token[0] == '+':
+			i.Tags = append(i.Tags, token[1:])
+		case len(token) > 1 && token[0] == '@':
+			i.Tags = append(i.Tags, token)
+		default:
+			key, value, ok := strings.Cut(token, ":")
+			if !ok || key == "" || value == "" || strings.HasPrefix(value, "//") {
+				text = append(text, token)
+				continue
+			}
+			known, err := i.setExtra(key, value)
+			if err != nil {
+				return item{}, fmt.Errorf("%w: %s: %w", ErrInvalidTodoTxt, token, err)
+			}
+			if !known {
+				text = append(text, token)
+			}
+		}
+	}
+	slices.Sort(i.Tags)
+	i.Tags = slices.Compact(i.Tags)
+	i.Task = strings.Join(text, " ")
+	if i.Task == "" {
+		return item{}, fmt.Errorf("%w: missing task description", ErrInvalidTodoTxt)
+	}
+	return i, nil
+}
+
+// escapeTodoTxt returns a word of a task with a backslash in front
+// when parseTodoTxtLine would not read it back as text. first tells
+// whether the word starts the task, following the completion marker,
+// priority and dates
+func escapeTodoTxt(word string, first bool) string {
+	escape := len(word) > 1 && strings.ContainsRune(`\+@`, rune(word[0]))
+	if key, value, ok := strings.Cut(word, ":"); ok && key != "" && value != "" && !strings.HasPrefix(value, "//") {
+		known, _ := (&item{}).setExtra(key, value)
+		escape = escape || known
+	}
+	if first {
+		_, err := time.Parse(time.DateOnly, word)
+		escape = escape || word == "x" || priorityRe.MatchString(word) || err == nil
+	}
+	if escape {
+		return `\` + word
+	}
+	return word
+}
+
+// setExtra sets the item field matching a key:value extra.
+// It returns false for keys it does not know about
+func (i *item) setExtra(key, value string) (bool, error) {
+	var err error
+	switch key {
+	case "id":
+		i.ID, err = strconv.Atoi(value)
+	case "parent":
+		i.Parent, err = strconv.Atoi(value)
+	case "dep":
+		i.DependsOn, err = parseIDs(value)
+	case "spawned":
+		i.Spawned, err = strconv.Atoi(value)
+	case "due":
+		i.Due, err = parseTodoTxtDue(value)
+	case "rec":
+		i.Recur, err = ParseRecurrence(value)
+	case "list":
+		if err = validListName(value); err == nil {
+			i.List = listField(value)
+		}
+	case "status":
+		if i.Done() {
+			break
+		}
+		if value == StatusDone || strings.ContainsFunc(value, unicode.IsSpace) {
+			err = ErrInvalidStatus
+			break
+		}
+		i.Status = value
+	case "pri":
+		if len(value) == 1 && value[0] >= 'A' && value[0] <= 'Z' {
+			i.Priority = value
+			break
+		}
+		err = ErrInvalidPriority
+	case "created":
+		i.CreatedAt, err = time.Parse(time.RFC3339Nano, value)
+	case "completed":
+		i.CompletedAt, err = time.Parse(time.RFC3339Nano, value)
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+// formatDue formats due as a date when it is due by the end of
+// the day in local time, and as a full timestamp otherwise
+func formatDue(due time.Time) string {
+	local := due.Local()
+	if local.Hour() == 23 && local.Minute() == 59 && local.Second() == 59 && local.Nanosecond() == 0 {
+		return local.Format(time.DateOnly)
+	}
+	return due.Format(time.RFC3339Nano)
+}
+
+// parseTodoTxtDue parses a due extra written by formatDue.
+// A date without time of day is due by the end of that day
+func parseTodoTxtDue(s string) (time.Time, error) {
+	if d, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return d, nil
+	}
+	d, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return d.AddDate(0, 0, 1).Add(-time.Second), nil
+}
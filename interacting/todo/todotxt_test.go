@@ -0,0 +1,180 @@
+package todo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pragprog.com/rggo/interacting/todo"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTodoTxt(t *testing.T) {
+	input := `(A) 2024-01-02 Call mom +family @phone due:2024-01-05
+x 2024-01-04 2024-01-01 Pay rent +home pri:B
+Read https://example.com/article later:maybe
+
+2024-01-03 Tag release id:7 rec:weekly:mon
+`
+	l, err := todo.ParseTodoTxt(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, l.Items, 4)
+
+	assert.Equal(t, "Call mom", l.Items[0].Task)
+	assert.Equal(t, "A", l.Items[0].Priority)
+	assert.False(t, l.Items[0].Done())
+	assert.Equal(t, []string{"@phone", "family"}, l.Items[0].Tags)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local), l.Items[0].CreatedAt)
+	assert.Equal(t, time.Date(2024, 1, 5, 23, 59, 59, 0, time.Local), l.Items[0].Due)
+
+	assert.True(t, l.Items[1].Done())
+	assert.Equal(t, "B", l.Items[1].Priority)
+	assert.Equal(t, time.Date(2024, 1, 4, 0, 0, 0, 0, time.Local), l.Items[1].CompletedAt)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), l.Items[1].CreatedAt)
+
+	assert.Equal(t, "Read https://example.com/article later:maybe", l.Items[2].Task, "expected unknown extras kept in the task")
+	assert.Equal(t, 0, l.Items[2].ID)
+
+	assert.Equal(t, 7, l.Items[3].ID)
+	require.NotNil(t, l.Items[3].Recur)
+	assert.Equal(t, "weekly:mon", l.Items[3].Recur.String())
+
+	_, err = todo.ParseTodoTxt(strings.NewReader("(A) due:2024-01-05\n"))
+	assert.ErrorIs(t, err, todo.ErrInvalidTodoTxt)
+	_, err = todo.ParseTodoTxt(strings.NewReader("Task id:one\n"))
+	assert.ErrorIs(t, err, todo.ErrInvalidTodoTxt)
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	l := todo.List{}
+	release := l.Add("Release")
+	tag, err := l.AddSub(release, "Tag")
+	require.NoError(t, err)
+	require.NoError(t, l.SetPriority(release, "A"))
+	require.NoError(t, l.AddTags(release, "work", "@office"))
+	require.NoError(t, l.SetDue(release, time.Date(2024, 1, 5, 15, 30, 0, 0, time.UTC)))
+	r, err := todo.ParseRecurrence("monthly:1")
+	require.NoError(t, err)
+	require.NoError(t, l.SetRecurrence(tag, r))
+	require.NoError(t, l.SetPriority(tag, "C"))
+	require.NoError(t, l.Complete(tag))
+	require.NoError(t, l.Move(release, "work"))
+	require.NoError(t, l.SetStatus(release, todo.StatusBlocked, todo.DefaultWorkflow))
+	// todo.txt does not keep the history of status transitions
+	for i := range l.Items {
+		l.Items[i].Transitions = nil
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, l.WriteTodoTxt(&buf))
+	parsed, err := todo.ParseTodoTxt(&buf)
+	require.NoError(t, err)
+
+	imported := todo.List{}
+	imported.Import(parsed)
+	exp, err := json.Marshal(l)
+	require.NoError(t, err)
+	got, err := json.Marshal(imported)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(exp), string(got))
+}
+
+func TestTodoTxtRoundTripText(t *testing.T) {
+	l := todo.List{}
+	for _, task := range []string{
+		"re: id:9",
+		"ask about status:quo and due:later",
+		"x marks the spot",
+		"(B) is a plan",
+		"2024-01-05 retro notes",
+		"email +team and @bob about \\escapes",
+		"see https://example.com and note:this",
+	} {
+		l.Add(task)
+	}
+	require.NoError(t, l.Complete(3))
+
+	var buf bytes.Buffer
+	require.NoError(t, l.WriteTodoTxt(&buf))
+	parsed, err := todo.ParseTodoTxt(&buf)
+	require.NoError(t, err)
+	require.Len(t, parsed.Items, len(l.Items))
+	for i, value := range parsed.Items {
+		assert.Equal(t, l.Items[i].Task, value.Task)
+		assert.Equal(t, l.Items[i].ID, value.ID)
+		assert.Equal(t, l.Items[i].Status, value.Status)
+		assert.Empty(t, value.Tags)
+		assert.True(t, value.Due.IsZero())
+	}
+}
+
+func TestList_Import(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	input := "x Task 1 from another list id:1\nSubtask parent:1 dep:9 id:2\nNew task dep:2\n"
+	items, err := todo.ParseTodoTxt(strings.NewReader(input))
+	require.NoError(t, err)
+	l.Import(items)
+	require.Len(t, l.Items, 5)
+	assert.Equal(t, "Task 1", l.Items[0].Task, "expected imported IDs not to replace items")
+	assert.Equal(t, []int{3, 4, 5}, []int{l.Items[2].ID, l.Items[3].ID, l.Items[4].ID})
+	assert.True(t, l.Items[2].Done())
+	assert.Equal(t, 3, l.Items[3].Parent, "expected the parent to follow its new ID")
+	assert.Empty(t, l.Items[3].DependsOn, "expected dependencies on items not imported to be dropped")
+	assert.Equal(t, []int{4}, l.Items[4].DependsOn)
+	assert.False(t, l.Items[4].CreatedAt.IsZero())
+}
+
+func TestList_ImportDependencies(t *testing.T) {
+	items, err := todo.ParseTodoTxt(strings.NewReader("A dep:2 id:1\nB dep:1 id:2\n"))
+	require.NoError(t, err)
+	l := todo.List{}
+	dropped := l.Import(items)
+	require.Len(t, dropped, 1)
+	assert.Equal(t, "2: B: dropped the dependency on 1 closing a cycle", dropped[0].String())
+	assert.Equal(t, []int{2}, l.Items[0].DependsOn)
+	assert.Empty(t, l.Items[1].DependsOn)
+
+	items, err = todo.ParseTodoTxt(strings.NewReader("C dep:9 id:3\n"))
+	require.NoError(t, err)
+	dropped = l.Sync(items)
+	require.Len(t, dropped, 1)
+	assert.Equal(t, "3: C: dropped the dependency on missing item 9", dropped[0].String())
+}
+
+func TestList_Sync(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	items, err := todo.ParseTodoTxt(strings.NewReader("x Task 1 renamed id:1\nNew task\n"))
+	require.NoError(t, err)
+	l.Sync(items)
+	require.Len(t, l.Items, 3)
+	assert.Equal(t, "Task 1 renamed", l.Items[0].Task)
+	assert.True(t, l.Items[0].Done())
+	assert.Equal(t, 3, l.Items[2].ID)
+	assert.Equal(t, "New task", l.Items[2].Task)
+
+	t.Run("Transitions", func(t *testing.T) {
+		require.NoError(t, l.SetStatus(2, todo.StatusBlocked, todo.DefaultWorkflow))
+		require.NoError(t, l.SetStatus(2, todo.StatusInProgress, todo.DefaultWorkflow))
+		var buf bytes.Buffer
+		require.NoError(t, l.WriteTodoTxt(&buf))
+		items, err := todo.ParseTodoTxt(&buf)
+		require.NoError(t, err)
+		before := l.Clone()
+		l.Sync(items)
+		assert.Equal(t, before.Items[1].Transitions, l.Items[1].Transitions,
+			"expected syncing an export to keep the status history")
+
+		items, err = todo.ParseTodoTxt(strings.NewReader("x Task 2 id:2\n"))
+		require.NoError(t, err)
+		l.Sync(items)
+		require.Len(t, l.Items[1].Transitions, 3)
+		assert.Equal(t, todo.StatusInProgress, l.Items[1].Transitions[2].From)
+		assert.Equal(t, todo.StatusDone, l.Items[1].Transitions[2].To)
+	})
+}